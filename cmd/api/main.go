@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humagin"
@@ -12,11 +14,12 @@ import (
 
 	"inventory-api/config"
 	"inventory-api/database"
+	"inventory-api/dtos"
 	"inventory-api/handler"
 	"inventory-api/middleware"
-	"inventory-api/models"
 	"inventory-api/repo"
 	"inventory-api/services"
+	"inventory-api/storage"
 )
 
 func main() {
@@ -31,7 +34,7 @@ func main() {
 
 	// Auto migrate models
 	db := database.GetDB()
-	if err := db.AutoMigrate(&models.Product{}, &models.Transaction{}, &models.User{}); err != nil {
+	if err := database.Migrate(db); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 	log.Println("Database migration completed")
@@ -39,14 +42,69 @@ func main() {
 	// Initialize repositories
 	inventoryRepo := repo.NewInventoryRepository(db)
 	userRepo := repo.NewUserRepository(db)
+	idempotencyRepo := repo.NewIdempotencyRepository(db)
+	attachmentRepo := repo.NewAttachmentRepository(db)
+	reportRepo := repo.NewReportRepository(db)
+	auditRepo := repo.NewAuditRepository(db)
+	jobRepo := repo.NewJobRepository(db)
+	forecastRepo := repo.NewForecastRepository(db)
+
+	// Initialize file storage
+	fileStorage, err := newStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize file storage:", err)
+	}
 
 	// Initialize services
-	inventoryService := services.NewInventoryService(inventoryRepo)
-	userService := services.NewUserService(userRepo, cfg.JWTSecret)
+	auditService := services.NewAuditService(auditRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, auditService)
+	userService := services.NewUserService(userRepo, cfg.JWTSecret, auditService)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL, cfg.IdempotencyLease)
+	attachmentService := services.NewAttachmentService(attachmentRepo, inventoryRepo, fileStorage, cfg.MaxImageSize, cfg.MaxAttachmentSize, auditService)
+	reportService := services.NewReportService(reportRepo, dtos.ABCOptions{
+		WindowDays: cfg.ABCWindowDays,
+		AThreshold: float64(cfg.ABCThresholdA),
+		BThreshold: float64(cfg.ABCThresholdB),
+	})
+	jobService := services.NewJobService(jobRepo, fileStorage, cfg.JobWorkers, cfg.JobPollInterval, cfg.JobMaxAttempts)
+	importService := services.NewImportService(inventoryRepo, auditService, jobService, cfg.MaxImportSize)
+	exportService := services.NewExportService(inventoryRepo)
+	forecastService := services.NewForecastService(forecastRepo, cfg.ForecastHistoryDays)
+	dashboardService := services.NewDashboardService(reportRepo, cfg.LowStockThreshold, cfg.DashboardCacheTTL)
+
+	// Register background job types and start the workers
+	jobService.Register(dtos.JobTypeProductImport, importService.ProductImportJob)
+	jobService.Register(dtos.JobTypeProductExport, exportService.ProductExportJob)
+	jobService.Register(dtos.JobTypeTransactionExport, exportService.TransactionExportJob)
+	jobService.Register(dtos.JobTypeUserMovementReport, reportService.UserMovementReportJob)
+	jobService.Register(dtos.JobTypeProductForecast, forecastService.ProductForecastJob)
+	jobService.Start(context.Background())
+
+	// Recalculate demand forecasts every night
+	jobService.ScheduleDaily(context.Background(), dtos.JobTypeProductForecast, dtos.ProductForecastJobParams{}, cfg.ForecastRunHour)
+
+	// Periodically remove expired idempotency keys
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := idempotencyService.PurgeExpired(); err != nil {
+				log.Println("Failed to purge expired idempotency keys:", err)
+			}
+		}
+	}()
 
 	// Initialize handlers
-	inventoryHandler := handler.NewInventoryHandler(inventoryService)
-	userHandler := handler.NewUserHandler(userService)
+	inventoryHandler := handler.NewInventoryHandler(inventoryService, idempotencyService, cfg.RequireIfMatch)
+	userHandler := handler.NewUserHandler(userService, cfg.RequireIfMatch)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService)
+	reportHandler := handler.NewReportHandler(reportService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	importHandler := handler.NewImportHandler(importService)
+	exportHandler := handler.NewExportHandler(exportService)
+	jobHandler := handler.NewJobHandler(jobService)
+	dashboardHandler := handler.NewDashboardHandler(dashboardService)
+	forecastHandler := handler.NewForecastHandler(forecastService)
 
 	// Setup Gin router
 	router := gin.Default()
@@ -67,6 +125,10 @@ func main() {
 
 	api := humagin.New(router, humaConfig)
 
+	// Make request details (e.g. URL for pagination links, request ID for the
+	// audit log) available to handlers
+	api.UseMiddleware(middleware.RequestInfoMiddleware(cfg.TrustedProxies))
+
 	// Add JWT middleware to protected routes
 	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
 		path := ctx.URL().Path
@@ -83,12 +145,31 @@ func main() {
 		// Apply auth middleware for protected routes
 		if strings.HasPrefix(path, "/users") ||
 			strings.HasPrefix(path, "/products") ||
-			strings.HasPrefix(path, "/transactions") {
-
-			// Allow public read access to products list and details
+			strings.HasPrefix(path, "/transactions") ||
+			strings.HasPrefix(path, "/categories") ||
+			strings.HasPrefix(path, "/reports") ||
+			strings.HasPrefix(path, "/jobs") ||
+			strings.HasPrefix(path, "/dashboard") ||
+			strings.HasPrefix(path, "/audit") {
+
+			// Allow public read access to products list and details, except the
+			// trash, revision history, stock history and forecasts, exports and
+			// listings that include deleted products
 			if (path == "/products" || strings.HasPrefix(path, "/products/")) &&
 				ctx.Method() == http.MethodGet &&
-				!strings.Contains(path, "/transactions") {
+				!strings.Contains(path, "/transactions") &&
+				!strings.Contains(path, "/revisions") &&
+				!strings.HasSuffix(path, "/timeseries") &&
+				!strings.HasSuffix(path, "/forecast") &&
+				!strings.HasPrefix(path, "/products/trash") &&
+				path != "/products/export" &&
+				ctx.Query("include_deleted") != "true" {
+				next(ctx)
+				return
+			}
+
+			// Categories are public to read like products
+			if path == "/categories" && ctx.Method() == http.MethodGet {
 				next(ctx)
 				return
 			}
@@ -103,6 +184,14 @@ func main() {
 	// Register routes
 	inventoryHandler.RegisterRoutes(api)
 	userHandler.RegisterRoutes(api)
+	attachmentHandler.RegisterRoutes(api)
+	reportHandler.RegisterRoutes(api)
+	auditHandler.RegisterRoutes(api)
+	importHandler.RegisterRoutes(api)
+	exportHandler.RegisterRoutes(api)
+	jobHandler.RegisterRoutes(api)
+	dashboardHandler.RegisterRoutes(api)
+	forecastHandler.RegisterRoutes(api)
 
 	// Get server port
 	port := cfg.ServerPort
@@ -116,3 +205,21 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// newStorage creates the file storage backend selected in the configuration
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageDriver {
+	case "local":
+		return storage.NewLocalStorage(cfg.StorageLocalDir)
+	case "s3":
+		return storage.NewS3Storage(context.Background(), storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage driver: %s", cfg.StorageDriver)
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +19,55 @@ type Config struct {
 	DBName     string
 	ServerPort string
 	JWTSecret  string
+
+	// IdempotencyTTL is how long stored Idempotency-Key responses are replayed,
+	// IdempotencyLease how long a key stays reserved by a request still running
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+
+	// RequireIfMatch rejects product and user updates without an If-Match header
+	RequireIfMatch bool
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header is
+	// believed when working out the client IP
+	TrustedProxies []netip.Prefix
+
+	// File storage: "local" or "s3"
+	StorageDriver     string
+	StorageLocalDir   string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3UseSSL          bool
+	MaxImageSize      int64
+	MaxAttachmentSize int64
+
+	// MaxImportSize limits product import files
+	MaxImportSize int64
+
+	// Background jobs: workers per replica, how often idle workers look for
+	// work and how many times a failing job is tried
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobMaxAttempts  int
+
+	// ABC analysis defaults: days of OUT movements to rank by and the
+	// cumulative value shares, in percent, covered by classes A and A+B
+	ABCWindowDays int
+	ABCThresholdA int
+	ABCThresholdB int
+
+	// Dashboard: how long computed KPIs are reused and the quantity at or
+	// below which an active product counts as low on stock
+	DashboardCacheTTL time.Duration
+	LowStockThreshold int
+
+	// Demand forecasting: days of OUT history models are fitted on and the UTC
+	// hour at which forecasts are recalculated every night
+	ForecastHistoryDays int
+	ForecastRunHour     int
 }
 
 func Load() *Config {
@@ -30,6 +83,38 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "inventory_db"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+
+		IdempotencyTTL:   getDurationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLease: getDurationEnv("IDEMPOTENCY_LEASE", time.Minute),
+		RequireIfMatch:   getBoolEnv("REQUIRE_IF_MATCH", false),
+		TrustedProxies:   getPrefixListEnv("TRUSTED_PROXIES"),
+
+		StorageDriver:     getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalDir:   getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", "localhost:9000"),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", "inventory"),
+		S3AccessKey:       getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:       getEnv("S3_SECRET_KEY", ""),
+		S3UseSSL:          getBoolEnv("S3_USE_SSL", false),
+		MaxImageSize:      int64(getIntEnv("MAX_IMAGE_SIZE_MB", 5)) << 20,
+		MaxAttachmentSize: int64(getIntEnv("MAX_ATTACHMENT_SIZE_MB", 10)) << 20,
+
+		MaxImportSize: int64(getIntEnv("MAX_IMPORT_SIZE_MB", 20)) << 20,
+
+		JobWorkers:      getIntEnv("JOB_WORKERS", 2),
+		JobPollInterval: getDurationEnv("JOB_POLL_INTERVAL", 2*time.Second),
+		JobMaxAttempts:  getIntEnv("JOB_MAX_ATTEMPTS", 3),
+
+		ABCWindowDays: getIntEnv("ABC_WINDOW_DAYS", 90),
+		ABCThresholdA: getIntEnv("ABC_THRESHOLD_A", 80),
+		ABCThresholdB: getIntEnv("ABC_THRESHOLD_B", 95),
+
+		DashboardCacheTTL: getDurationEnv("DASHBOARD_CACHE_TTL", time.Minute),
+		LowStockThreshold: getIntEnv("LOW_STOCK_THRESHOLD", 10),
+
+		ForecastHistoryDays: getIntEnv("FORECAST_HISTORY_DAYS", 365),
+		ForecastRunHour:     getIntEnv("FORECAST_RUN_HOUR", 2),
 	}
 }
 
@@ -39,3 +124,65 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getPrefixListEnv parses a comma-separated list of CIDR ranges or single IP
+// addresses, skipping invalid entries
+func getPrefixListEnv(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			log.Printf("Invalid IP range in %s: %q, ignoring it", key, entry)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
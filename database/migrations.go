@@ -0,0 +1,118 @@
+package database
+
+import (
+	"fmt"
+	"inventory-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Migrate creates or updates the schema for all models and applies
+// changes AutoMigrate cannot express on its own.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&models.Category{},
+		&models.Product{},
+		&models.Transaction{},
+		&models.User{},
+		&models.IdempotencyKey{},
+		&models.ProductImage{},
+		&models.TransactionAttachment{},
+		&models.AuditLog{},
+		&models.ProductRevision{},
+		&models.Job{},
+		&models.ProductForecast{},
+	); err != nil {
+		return err
+	}
+
+	// SKU uniqueness only applies to non-deleted products so a deleted SKU can be reused.
+	// Drop the old full unique index that also covered soft-deleted rows.
+	if db.Migrator().HasIndex(&models.Product{}, "idx_products_sku") {
+		if err := db.Migrator().DropIndex(&models.Product{}, "idx_products_sku"); err != nil {
+			return fmt.Errorf("failed to drop legacy SKU index: %w", err)
+		}
+	}
+
+	if err := migrateProductSearch(db); err != nil {
+		return fmt.Errorf("failed to migrate product search: %w", err)
+	}
+
+	if err := migrateAuditLog(db); err != nil {
+		return fmt.Errorf("failed to migrate audit log: %w", err)
+	}
+
+	if err := migrateStockLedger(db); err != nil {
+		return fmt.Errorf("failed to migrate stock ledger: %w", err)
+	}
+
+	return nil
+}
+
+// migrateProductSearch adds the full-text search vector and trigram indexes used by
+// product search. The vector is a generated column so it never goes stale.
+func migrateProductSearch(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(sku, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAuditLog makes the audit log append-only at the database level, so rows
+// cannot be changed even by code paths that bypass the service layer
+func migrateAuditLog(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'audit_log is append-only';
+			END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_log_immutable ON audit_log`,
+		`CREATE TRIGGER audit_log_immutable BEFORE UPDATE OR DELETE ON audit_log
+			FOR EACH ROW EXECUTE FUNCTION audit_log_immutable()`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateStockLedger records, once, an opening balance adjustment for every
+// product whose quantity the IN/OUT movements do not explain, dated when the
+// product was created. Quantity changes made before adjustments were recorded
+// would otherwise be missing from balances rebuilt from the ledger. The advisory
+// lock keeps replicas starting together from backfilling twice.
+func migrateStockLedger(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('migrate_stock_ledger'))`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO transactions (product_id, quantity, transaction_type, notes, created_at, updated_at)
+			SELECT products.id, products.quantity - COALESCE(ledger.net, 0), ?, 'Opening balance', products.created_at, products.created_at
+			FROM products
+			LEFT JOIN (
+				SELECT product_id, SUM(CASE WHEN transaction_type = ? THEN -quantity ELSE quantity END) AS net
+				FROM transactions
+				GROUP BY product_id
+			) ledger ON ledger.product_id = products.id
+			WHERE products.quantity <> COALESCE(ledger.net, 0)
+			AND NOT EXISTS (SELECT 1 FROM transactions WHERE transaction_type = ?)`,
+			models.TransactionTypeAdjust, models.TransactionTypeOut, models.TransactionTypeAdjust).Error
+	})
+}
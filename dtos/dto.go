@@ -1,10 +1,17 @@
 package dtos
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type TransactionType string
 
 const (
-	TransactionTypeIn  TransactionType = "IN"
-	TransactionTypeOut TransactionType = "OUT"
+	TransactionTypeIn     TransactionType = "IN"
+	TransactionTypeOut    TransactionType = "OUT"
+	TransactionTypeAdjust TransactionType = "ADJUST"
 )
 
 // Product DTOs
@@ -14,6 +21,8 @@ type CreateProductInput struct {
 	Description string  `json:"description,omitempty" doc:"Product description"`
 	Price       float64 `json:"price" minimum:"0.01" doc:"Product price (must be greater than 0)"`
 	Quantity    int     `json:"quantity" minimum:"1" doc:"Initial quantity (must be at least 1)"`
+	Status      string  `json:"status,omitempty" enum:"draft,active" doc:"Initial lifecycle status (default active)"`
+	CategoryID  *uint   `json:"category_id,omitempty" doc:"Category ID"`
 }
 
 type UpdateProductInput struct {
@@ -21,6 +30,7 @@ type UpdateProductInput struct {
 	Description *string  `json:"description,omitempty" doc:"Product description"`
 	Price       *float64 `json:"price,omitempty" minimum:"0" doc:"Product price (can be 0)"`
 	Quantity    *int     `json:"quantity,omitempty" minimum:"0" doc:"Product quantity (can be 0)"`
+	CategoryID  *uint    `json:"category_id,omitempty" doc:"Category ID"`
 }
 
 type ProductResponse struct {
@@ -30,8 +40,91 @@ type ProductResponse struct {
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	Quantity    int     `json:"quantity"`
+	Version     uint    `json:"version"`
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
+	DeletedAt   *string `json:"deleted_at,omitempty"`
+
+	Status          string  `json:"status"`
+	StatusChangedAt *string `json:"status_changed_at,omitempty"`
+	StatusChangedBy *uint   `json:"status_changed_by,omitempty"`
+	CategoryID      *uint   `json:"category_id,omitempty"`
+	ABCClass        string  `json:"abc_class,omitempty" doc:"Class from the last stored ABC analysis"`
+
+	// Only set for search results
+	Rank      *float64 `json:"rank,omitempty"`
+	Highlight *string  `json:"highlight,omitempty" doc:"HTML-escaped snippet with the matches wrapped in <mark>"`
+
+	// Fields requested with fields=, nil returns all
+	fields []string
+}
+
+// SelectFields limits the JSON output to the given fields
+func (p *ProductResponse) SelectFields(fields []string) {
+	p.fields = fields
+}
+
+// MarshalJSON omits fields that were not requested with fields=
+func (p ProductResponse) MarshalJSON() ([]byte, error) {
+	type plain ProductResponse
+	return marshalFields(plain(p), p.fields, ProductFields)
+}
+
+type ChangeProductStatusInput struct {
+	Status string `json:"status" enum:"draft,active,discontinued,archived" doc:"New lifecycle status"`
+}
+
+// Bulk product operations
+const (
+	BulkOperationSetPrice    = "set_price"
+	BulkOperationAdjustPrice = "adjust_price"
+	BulkOperationSetStatus   = "set_status"
+	BulkOperationSetCategory = "set_category"
+	BulkOperationDelete      = "delete"
+)
+
+// Price rounding rules for bulk price changes
+const (
+	PriceRoundingCents    = "cents"
+	PriceRoundingWhole    = "whole"
+	PriceRoundingEnding99 = "ending_99"
+)
+
+// BulkProductInput is one operation applied to many products, chosen either by
+// ID or by a filter
+type BulkProductInput struct {
+	Operation  string         `json:"operation" enum:"set_price,adjust_price,set_status,set_category,delete" doc:"Operation to apply; delete is admin only"`
+	IDs        []uint         `json:"ids,omitempty" maxItems:"1000" doc:"Products to change; give either ids or filter"`
+	Filter     *ProductFilter `json:"filter,omitempty" doc:"Change every product matching the filter; uses the same names as the list query parameters, and matches all statuses unless status is given"`
+	Price      *float64       `json:"price,omitempty" minimum:"0" doc:"New price for set_price"`
+	Percent    *float64       `json:"percent,omitempty" doc:"Percentage to add to the price for adjust_price, negative to lower it"`
+	Amount     *float64       `json:"amount,omitempty" doc:"Amount to add to the price for adjust_price, negative to lower it"`
+	Rounding   string         `json:"rounding,omitempty" enum:"cents,whole,ending_99" default:"cents" doc:"How new prices are rounded: to the cent, to a whole amount, or to the nearest price ending in .99"`
+	Status     string         `json:"status,omitempty" enum:"draft,active,discontinued,archived" doc:"New lifecycle status for set_status"`
+	CategoryID *uint          `json:"category_id,omitempty" doc:"Category for set_category; omit or null to remove the category"`
+}
+
+// BulkProductResult is the outcome of a bulk operation on one product
+type BulkProductResult struct {
+	ProductID uint             `json:"product_id"`
+	SKU       string           `json:"sku,omitempty"`
+	Action    string           `json:"action" enum:"update,delete,unchanged,invalid"`
+	Before    *ProductResponse `json:"before,omitempty"`
+	After     *ProductResponse `json:"after,omitempty" doc:"State after the operation; omitted for deletes and invalid products"`
+	Errors    []string         `json:"errors,omitempty"`
+}
+
+// BulkProductReport summarizes a bulk operation. In a dry run the counts describe
+// what would happen.
+type BulkProductReport struct {
+	DryRun    bool                `json:"dry_run"`
+	Operation string              `json:"operation"`
+	Matched   int                 `json:"matched"`
+	Updated   int                 `json:"updated"`
+	Deleted   int                 `json:"deleted"`
+	Unchanged int                 `json:"unchanged"`
+	Invalid   int                 `json:"invalid"`
+	Products  []BulkProductResult `json:"products"`
 }
 
 // Transaction DTOs
@@ -46,11 +139,95 @@ type TransactionResponse struct {
 	ID              uint             `json:"id"`
 	ProductID       uint             `json:"product_id"`
 	Product         *ProductResponse `json:"product,omitempty"`
-	Quantity        int              `json:"quantity"`
-	TransactionType TransactionType  `json:"transaction_type"`
+	Quantity        int              `json:"quantity" doc:"Quantity moved; for ADJUST the signed change"`
+	TransactionType TransactionType  `json:"transaction_type" enum:"IN,OUT,ADJUST"`
+	Reference       string           `json:"reference,omitempty"`
 	Notes           string           `json:"notes"`
+	CreatedBy       *uint            `json:"created_by,omitempty" doc:"ID of the user who recorded the movement, empty for movements recorded before it was tracked"`
+	User            *UserSummary     `json:"user,omitempty"`
 	CreatedAt       string           `json:"created_at"`
 	UpdatedAt       string           `json:"updated_at"`
+
+	// Fields requested with fields=, nil returns all
+	fields []string
+}
+
+// TransactionExportRecord is one line of a transaction ledger export
+type TransactionExportRecord struct {
+	ID              uint            `json:"id"`
+	CreatedAt       string          `json:"created_at"`
+	ProductID       uint            `json:"product_id"`
+	ProductSKU      string          `json:"product_sku"`
+	ProductName     string          `json:"product_name"`
+	TransactionType TransactionType `json:"transaction_type"`
+	Quantity        int             `json:"quantity"`
+	Reference       string          `json:"reference,omitempty"`
+	Notes           string          `json:"notes"`
+	CreatedBy       *uint           `json:"created_by,omitempty"`
+	CreatedByName   string          `json:"created_by_username,omitempty"`
+}
+
+// SelectFields limits the JSON output to the given fields
+func (t *TransactionResponse) SelectFields(fields []string) {
+	t.fields = fields
+}
+
+// MarshalJSON omits fields that were not requested with fields=
+func (t TransactionResponse) MarshalJSON() ([]byte, error) {
+	type plain TransactionResponse
+	return marshalFields(plain(t), t.fields, TransactionFields)
+}
+
+// CreateTransactionBatchInput groups many movements under one document reference
+type CreateTransactionBatchInput struct {
+	Reference string                   `json:"reference" minLength:"1" maxLength:"100" doc:"Document reference (e.g. delivery note number)"`
+	Lines     []CreateTransactionInput `json:"lines" minItems:"1" maxItems:"500" doc:"Transaction lines, applied all-or-nothing"`
+}
+
+type TransactionBatchResponse struct {
+	Reference    string                `json:"reference"`
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+// BatchLineError describes why a single line of a batch was rejected
+type BatchLineError struct {
+	Line    int         `json:"line"`
+	Field   string      `json:"field"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value,omitempty"`
+}
+
+// BatchValidationError is returned when one or more lines of a batch are invalid
+type BatchValidationError struct {
+	Errors []BatchLineError
+}
+
+func (e *BatchValidationError) Error() string {
+	return fmt.Sprintf("batch rejected: %d invalid line(s)", len(e.Errors))
+}
+
+// File DTOs
+type ProductImageResponse struct {
+	ID           uint   `json:"id"`
+	ProductID    uint   `json:"product_id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	UploadedBy   uint   `json:"uploaded_by"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type AttachmentResponse struct {
+	ID            uint   `json:"id"`
+	TransactionID uint   `json:"transaction_id"`
+	FileName      string `json:"file_name"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	URL           string `json:"url"`
+	UploadedBy    uint   `json:"uploaded_by"`
+	CreatedAt     string `json:"created_at"`
 }
 
 type ProductFilter struct {
@@ -58,13 +235,26 @@ type ProductFilter struct {
 	Name     *string  `json:"name,omitempty"`
 	MinPrice *float64 `json:"min_price,omitempty"`
 	MaxPrice *float64 `json:"max_price,omitempty"`
+	Status   *string  `json:"status,omitempty"`
+	Query    *string  `json:"q,omitempty"`
+
+	CategoryID *uint   `json:"category_id,omitempty"`
+	ABCClass   *string `json:"abc_class,omitempty"`
+
+	CreatedFrom *time.Time `json:"created_from,omitempty"`
+	CreatedTo   *time.Time `json:"created_to,omitempty"`
+	MinQty      *int       `json:"min_qty,omitempty"`
+	MaxQty      *int       `json:"max_qty,omitempty"`
+
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
 }
 
 func (f *ProductFilter) IsEmpty() bool {
 	if f == nil {
 		return true
 	}
-	return f.SKU == nil && f.Name == nil && f.MinPrice == nil && f.MaxPrice == nil
+	return f.SKU == nil && f.Name == nil && f.MinPrice == nil && f.MaxPrice == nil && f.Status == nil && f.Query == nil &&
+		f.CreatedFrom == nil && f.CreatedTo == nil && f.MinQty == nil && f.MaxQty == nil && f.CategoryID == nil && f.ABCClass == nil && !f.IncludeDeleted
 }
 
 func (f *ProductFilter) HasSKU() bool {
@@ -83,6 +273,358 @@ func (f *ProductFilter) HasMaxPrice() bool {
 	return f != nil && f.MaxPrice != nil
 }
 
+func (f *ProductFilter) HasCreatedFrom() bool {
+	return f != nil && f.CreatedFrom != nil
+}
+
+func (f *ProductFilter) HasCreatedTo() bool {
+	return f != nil && f.CreatedTo != nil
+}
+
+func (f *ProductFilter) HasMinQty() bool {
+	return f != nil && f.MinQty != nil
+}
+
+func (f *ProductFilter) HasMaxQty() bool {
+	return f != nil && f.MaxQty != nil
+}
+
+func (f *ProductFilter) HasQuery() bool {
+	return f != nil && f.Query != nil && *f.Query != ""
+}
+
+func (f *ProductFilter) HasCategoryID() bool {
+	return f != nil && f.CategoryID != nil
+}
+
+func (f *ProductFilter) HasABCClass() bool {
+	return f != nil && f.ABCClass != nil && *f.ABCClass != ""
+}
+
+func (f *ProductFilter) HasStatus() bool {
+	return f != nil && f.Status != nil && *f.Status != ""
+}
+
+type TransactionFilter struct {
+	Type      *string    `json:"type,omitempty"`
+	ProductID *uint      `json:"product_id,omitempty"`
+	UserID    *uint      `json:"user_id,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	Notes     *string    `json:"notes,omitempty"`
+}
+
+func (f *TransactionFilter) IsEmpty() bool {
+	if f == nil {
+		return true
+	}
+	return f.Type == nil && f.ProductID == nil && f.UserID == nil && f.From == nil && f.To == nil && f.Notes == nil
+}
+
+func (f *TransactionFilter) HasType() bool {
+	return f != nil && f.Type != nil && *f.Type != ""
+}
+
+func (f *TransactionFilter) HasProductID() bool {
+	return f != nil && f.ProductID != nil && *f.ProductID != 0
+}
+
+func (f *TransactionFilter) HasUserID() bool {
+	return f != nil && f.UserID != nil && *f.UserID != 0
+}
+
+func (f *TransactionFilter) HasFrom() bool {
+	return f != nil && f.From != nil
+}
+
+func (f *TransactionFilter) HasTo() bool {
+	return f != nil && f.To != nil
+}
+
+func (f *TransactionFilter) HasNotes() bool {
+	return f != nil && f.Notes != nil && *f.Notes != ""
+}
+
+// AuditLogResponse is one audit trail entry. Before and After are snapshots of the
+// entity, Changes maps each changed field to its old and new value.
+type AuditLogResponse struct {
+	ID        uint            `json:"id"`
+	ActorID   *uint           `json:"actor_id,omitempty"`
+	ActorName string          `json:"actor_name,omitempty"`
+	Entity    string          `json:"entity"`
+	EntityID  *uint           `json:"entity_id,omitempty"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Changes   json.RawMessage `json:"changes,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	Hash      string          `json:"hash"`
+	PrevHash  string          `json:"prev_hash,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// AuditVerifyResponse reports whether the audit hash chain is intact
+type AuditVerifyResponse struct {
+	Valid          bool   `json:"valid"`
+	Checked        int64  `json:"checked"`
+	FirstInvalidID *uint  `json:"first_invalid_id,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// AuditFilter represents filter criteria for the audit log
+type AuditFilter struct {
+	ActorID   *uint
+	Entity    *string
+	EntityID  *uint
+	Action    *string
+	RequestID *string
+	From      *time.Time
+	To        *time.Time
+}
+
+// UserMovementSummary totals the stock movements recorded by one user
+type UserMovementSummary struct {
+	UserID      *uint  `json:"user_id" doc:"Empty for movements recorded before the acting user was tracked"`
+	Username    string `json:"username,omitempty"`
+	InCount     int64  `json:"in_count"`
+	InQuantity  int64  `json:"in_quantity"`
+	OutCount    int64  `json:"out_count"`
+	OutQuantity int64  `json:"out_quantity"`
+	LastMovedAt string `json:"last_moved_at"`
+}
+
+// ABC classes, A being the products with the highest consumption value
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+)
+
+// ABCOptions are the window and cumulative share thresholds of an ABC analysis.
+// Zero values take the configured defaults.
+type ABCOptions struct {
+	WindowDays int     `json:"window_days"`
+	AThreshold float64 `json:"a_threshold"`
+	BThreshold float64 `json:"b_threshold"`
+}
+
+// ABCProductResult is the rank and class of one product in an ABC analysis
+type ABCProductResult struct {
+	Rank             int     `json:"rank"`
+	ProductID        uint    `json:"product_id"`
+	SKU              string  `json:"sku"`
+	Name             string  `json:"name"`
+	Price            float64 `json:"price"`
+	OutQuantity      int64   `json:"out_quantity"`
+	ConsumptionValue float64 `json:"consumption_value" doc:"OUT quantity in the window times the current price"`
+	Share            float64 `json:"share" doc:"Percentage of the total consumption value"`
+	CumulativeShare  float64 `json:"cumulative_share" doc:"Percentage of the total covered by this and all higher ranked products"`
+	Class            string  `json:"class" enum:"A,B,C"`
+}
+
+// ABCClassSummary totals one class of an ABC analysis
+type ABCClassSummary struct {
+	Class            string  `json:"class" enum:"A,B,C"`
+	Products         int     `json:"products"`
+	ConsumptionValue float64 `json:"consumption_value"`
+	Share            float64 `json:"share"`
+}
+
+// ABCReport ranks products by consumption value and assigns their ABC classes
+type ABCReport struct {
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	WindowDays int                `json:"window_days"`
+	AThreshold float64            `json:"a_threshold"`
+	BThreshold float64            `json:"b_threshold"`
+	Applied    bool               `json:"applied" doc:"Whether the classes were stored on the products"`
+	TotalValue float64            `json:"total_value"`
+	Classes    []ABCClassSummary  `json:"classes"`
+	Products   []ABCProductResult `json:"products"`
+}
+
+// AgingOptions selects and orders the products of the dead stock and aging report
+type AgingOptions struct {
+	Days          int
+	CategoryID    *uint
+	ABCClass      *string
+	IncludeMoving bool
+	Sort          string
+}
+
+// AgingBucketAmount is the stock of one age bucket
+type AgingBucketAmount struct {
+	Bucket   string  `json:"bucket" doc:"Age range in days, e.g. 31-60 or 366+"`
+	Quantity int64   `json:"quantity"`
+	Value    float64 `json:"value"`
+}
+
+// ProductAging is the on-hand stock of one product split by age
+type ProductAging struct {
+	ProductID  uint                `json:"product_id"`
+	SKU        string              `json:"sku"`
+	Name       string              `json:"name"`
+	CategoryID *uint               `json:"category_id,omitempty"`
+	Price      float64             `json:"price"`
+	Quantity   int                 `json:"quantity"`
+	Value      float64             `json:"value" doc:"Value tied up in the stock: quantity times the current price"`
+	LastInAt   *string             `json:"last_in_at,omitempty"`
+	LastOutAt  *string             `json:"last_out_at,omitempty"`
+	IdleDays   int                 `json:"idle_days" doc:"Days since the last OUT movement, or since the product was created if it never moved out"`
+	Dead       bool                `json:"dead" doc:"No OUT movement within the report's days"`
+	Buckets    []AgingBucketAmount `json:"buckets"`
+}
+
+// AgingReport lists dead stock and the age of on-hand quantity
+type AgingReport struct {
+	AsOf          string              `json:"as_of"`
+	Days          int                 `json:"days"`
+	Products      []ProductAging      `json:"products"`
+	TotalQuantity int64               `json:"total_quantity"`
+	TotalValue    float64             `json:"total_value"`
+	Buckets       []AgingBucketAmount `json:"buckets"`
+}
+
+// DashboardOptions selects the window of the KPI dashboard. When UserID is set,
+// movement metrics only cover the transactions that user recorded.
+type DashboardOptions struct {
+	Days   int
+	Top    int
+	UserID *uint
+}
+
+// Scopes of the KPI dashboard
+const (
+	DashboardScopeAll = "all"
+	DashboardScopeOwn = "own"
+)
+
+// DashboardMover is one of the products with the most stock moved out in the window
+type DashboardMover struct {
+	ProductID   uint    `json:"product_id"`
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	OutQuantity int64   `json:"out_quantity"`
+	OutValue    float64 `json:"out_value" doc:"OUT quantity times the current price"`
+}
+
+// DashboardTrendPoint totals the IN and OUT quantities of one UTC day
+type DashboardTrendPoint struct {
+	Date        string `json:"date" doc:"UTC day, YYYY-MM-DD"`
+	InQuantity  int64  `json:"in_quantity"`
+	OutQuantity int64  `json:"out_quantity"`
+}
+
+// Dashboard holds the inventory KPIs. Stock figures cover all products; turnover
+// and days of supply are only computed for the all scope.
+type Dashboard struct {
+	GeneratedAt       string                `json:"generated_at" doc:"When the figures were computed; they may be served from cache until the cache interval elapses"`
+	Scope             string                `json:"scope" enum:"all,own" doc:"own when top movers and the trend only cover the caller's transactions"`
+	Days              int                   `json:"days"`
+	From              string                `json:"from" doc:"Start of the window, midnight UTC"`
+	ProductCount      int64                 `json:"product_count"`
+	TotalQuantity     int64                 `json:"total_quantity"`
+	TotalStockValue   float64               `json:"total_stock_value" doc:"On-hand quantity times the current price"`
+	StockoutCount     int64                 `json:"stockout_count" doc:"Active products with no stock"`
+	LowStockCount     int64                 `json:"low_stock_count" doc:"Active products with stock at or below the low stock threshold"`
+	LowStockThreshold int                   `json:"low_stock_threshold"`
+	TurnoverRatio     *float64              `json:"turnover_ratio,omitempty" doc:"Value moved out in the window divided by the average stock value"`
+	DaysOfSupply      *float64              `json:"days_of_supply,omitempty" doc:"On-hand quantity divided by the average daily OUT quantity in the window"`
+	TopMovers         []DashboardMover      `json:"top_movers"`
+	Trend             []DashboardTrendPoint `json:"trend"`
+}
+
+// Periods of the stock movement summary
+const (
+	MovementPeriodDay   = "day"
+	MovementPeriodWeek  = "week"
+	MovementPeriodMonth = "month"
+)
+
+// MovementFilter selects the range, periods and products of the stock movement summary
+type MovementFilter struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	GroupBy    string    `json:"group_by"`
+	ProductID  *uint     `json:"product_id,omitempty"`
+	CategoryID *uint     `json:"category_id,omitempty"`
+}
+
+// ProductMovementSummary is the stock of one product over one period
+type ProductMovementSummary struct {
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+	ProductID   uint   `json:"product_id"`
+	SKU         string `json:"sku"`
+	Name        string `json:"name"`
+	Opening     int64  `json:"opening"`
+	In          int64  `json:"in"`
+	Out         int64  `json:"out"`
+	Adjustments int64  `json:"adjustments" doc:"Net quantity changes made outside IN/OUT movements"`
+	Closing     int64  `json:"closing"`
+}
+
+// StockLevelPoint is the stock level of a product over one bucket of a time series
+type StockLevelPoint struct {
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+	Opening     int64  `json:"opening" doc:"Stock level at the start of the bucket"`
+	In          int64  `json:"in"`
+	Out         int64  `json:"out"`
+	Adjustments int64  `json:"adjustments" doc:"Net ADJUST change"`
+	Closing     int64  `json:"closing" doc:"Stock level at the end of the bucket"`
+}
+
+// StockTimeseries is the stock level of one product per bucket, with every bucket
+// of the range present
+type StockTimeseries struct {
+	ProductID uint              `json:"product_id"`
+	SKU       string            `json:"sku"`
+	Name      string            `json:"name"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Interval  string            `json:"interval"`
+	Points    []StockLevelPoint `json:"points"`
+}
+
+// Demand forecasting models
+const (
+	ForecastModelMovingAverage7  = "moving_average_7"
+	ForecastModelMovingAverage28 = "moving_average_28"
+	ForecastModelHoltWinters     = "holt_winters"
+)
+
+// MaxForecastHorizon is how many days ahead demand is forecast
+const MaxForecastHorizon = 90
+
+// ForecastPoint is the forecast demand of one UTC day
+type ForecastPoint struct {
+	Date     string  `json:"date" doc:"UTC day, YYYY-MM-DD"`
+	Quantity float64 `json:"quantity" doc:"Forecast OUT quantity"`
+	Lower    float64 `json:"lower" doc:"Lower bound of the 95% prediction interval"`
+	Upper    float64 `json:"upper" doc:"Upper bound of the 95% prediction interval"`
+}
+
+// ForecastModelScore is the backtest error of one candidate model
+type ForecastModelScore struct {
+	Model string  `json:"model" enum:"moving_average_7,moving_average_28,holt_winters"`
+	MAE   float64 `json:"mae" doc:"Mean absolute error per day on the held out days"`
+	RMSE  float64 `json:"rmse" doc:"Root mean squared error per day on the held out days"`
+}
+
+// ProductForecast is the daily demand forecast of a product
+type ProductForecast struct {
+	ProductID   uint                 `json:"product_id"`
+	SKU         string               `json:"sku"`
+	Name        string               `json:"name"`
+	Model       string               `json:"model" enum:"moving_average_7,moving_average_28,holt_winters" doc:"Model with the lowest backtest MAE"`
+	GeneratedAt string               `json:"generated_at"`
+	HistoryDays int                  `json:"history_days" doc:"Days of OUT history the model was fitted on"`
+	Candidates  []ForecastModelScore `json:"candidates" doc:"Backtest errors of the models tried; empty when the history is too short to backtest"`
+	Points      []ForecastPoint      `json:"points"`
+}
+
 // User DTOs
 type RegisterInput struct {
 	Username string `json:"username" minLength:"3" maxLength:"50" pattern:"^[a-zA-Z0-9_]+$" doc:"Username (alphanumeric and underscore only)"`
@@ -114,4 +656,160 @@ type UserResponse struct {
 	Email    string `json:"email"`
 	Phone    string `json:"phone"`
 	Role     string `json:"role"`
+	Version  uint   `json:"version"`
+}
+
+// ProductRevisionResponse is a stored snapshot of a product after an update
+type ProductRevisionResponse struct {
+	Revision       uint `json:"revision"`
+	ProductVersion uint `json:"product_version"`
+	ProductRevisionFields
+	ChangedBy *uint  `json:"changed_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ProductRevisionFields are the product fields captured by a revision
+type ProductRevisionFields struct {
+	Name        string  `json:"name"`
+	SKU         string  `json:"sku"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Quantity    int     `json:"quantity"`
+	Status      string  `json:"status"`
+	CategoryID  *uint   `json:"category_id"`
+}
+
+// FieldChange is one field that differs between two revisions
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// ProductRevisionDiff lists what changed in a revision compared to the one before it
+type ProductRevisionDiff struct {
+	Revision         uint          `json:"revision"`
+	PreviousRevision *uint         `json:"previous_revision,omitempty"`
+	Changes          []FieldChange `json:"changes"`
+}
+
+type CategoryResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CreateCategoryInput struct {
+	Name string `json:"name" minLength:"1" maxLength:"100" doc:"Category name"`
+}
+
+// ProductImportColumns are the product fields an import file can provide
+var ProductImportColumns = []string{"sku", "name", "description", "price", "quantity", "status", "category"}
+
+// ImportOptions controls how a product import file is read and applied
+type ImportOptions struct {
+	Format string `json:"format,omitempty"`
+	Sheet  string `json:"sheet,omitempty"`
+	// Mapping maps product fields to column headers; unmapped fields use their own name
+	Mapping map[string]string `json:"mapping,omitempty"`
+	DryRun  bool              `json:"dry_run,omitempty"`
+}
+
+// ImportRowResult is the outcome of one data row of an import file
+type ImportRowResult struct {
+	Row       int      `json:"row" doc:"Row number in the file, the header being row 1"`
+	SKU       string   `json:"sku,omitempty"`
+	Action    string   `json:"action" enum:"create,update,unchanged,invalid,failed"`
+	ProductID *uint    `json:"product_id,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// ImportReport summarizes a product import. In a dry run the counts describe
+// what would happen.
+type ImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Format    string            `json:"format"`
+	TotalRows int               `json:"total_rows"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Invalid   int               `json:"invalid"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows,omitempty"`
+}
+
+// Background job types
+const (
+	JobTypeProductImport      = "product_import"
+	JobTypeProductExport      = "product_export"
+	JobTypeTransactionExport  = "transaction_export"
+	JobTypeUserMovementReport = "user_movement_report"
+	JobTypeProductForecast    = "product_forecast"
+)
+
+// JobResponse describes a background job and, once it succeeded, its result
+type JobResponse struct {
+	ID              uint            `json:"id"`
+	Type            string          `json:"type"`
+	Status          string          `json:"status" enum:"queued,running,succeeded,failed,cancelled"`
+	Progress        int             `json:"progress" doc:"Completion percentage"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	Error           string          `json:"error,omitempty"`
+	Result          json.RawMessage `json:"result,omitempty" doc:"Summary produced by the job, e.g. the counts of an import"`
+	ResultName      string          `json:"result_name,omitempty" doc:"File name of the downloadable result"`
+	ResultSize      int64           `json:"result_size,omitempty"`
+	CreatedBy       *uint           `json:"created_by,omitempty"`
+	CancelRequested bool            `json:"cancel_requested,omitempty"`
+	StartedAt       *string         `json:"started_at,omitempty"`
+	FinishedAt      *string         `json:"finished_at,omitempty"`
+	CreatedAt       string          `json:"created_at"`
+	UpdatedAt       string          `json:"updated_at"`
+}
+
+// JobFilter narrows a job listing
+type JobFilter struct {
+	CreatedBy *uint
+	Status    *string
+	Type      *string
+}
+
+// ExportFormats are the formats products and transactions can be exported as
+var ExportFormats = []string{"csv", "xlsx", "ndjson"}
+
+// ProductExportJobParams are the parameters of a product_export job
+type ProductExportJobParams struct {
+	Format string        `json:"format"`
+	Filter ProductFilter `json:"filter"`
+}
+
+// TransactionExportJobParams are the parameters of a transaction_export job
+type TransactionExportJobParams struct {
+	Format string            `json:"format"`
+	Filter TransactionFilter `json:"filter"`
+}
+
+// UserMovementReportJobParams are the parameters of a user_movement_report job
+type UserMovementReportJobParams struct {
+	Filter TransactionFilter `json:"filter"`
+}
+
+// ProductForecastJobParams are the parameters of a product_forecast job. Without
+// product IDs every product that is not archived is forecast.
+type ProductForecastJobParams struct {
+	ProductIDs []uint `json:"product_ids,omitempty"`
+}
+
+// ProductImportJobParams are the parameters of a product_import job; the file
+// itself is stored as the job's input
+type ProductImportJobParams struct {
+	FileName string        `json:"file_name"`
+	Options  ImportOptions `json:"options"`
+}
+
+// UserSummary identifies a user embedded in another resource
+type UserSummary struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
 }
@@ -0,0 +1,94 @@
+package dtos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Selectable response fields and expandable relations for read endpoints.
+// Field names match both the JSON keys and the database columns.
+var (
+	ProductFields = []string{
+		"id", "name", "sku", "description", "price", "quantity", "version", "status",
+		"status_changed_at", "status_changed_by", "category_id", "abc_class", "created_at", "updated_at", "deleted_at",
+	}
+	TransactionFields = []string{
+		"id", "product_id", "quantity", "transaction_type", "reference", "notes", "created_by", "created_at", "updated_at",
+	}
+	TransactionExpansions = []string{"product", "user"}
+)
+
+// Projection selects which columns to load and which relations to preload.
+// A nil Fields loads every column.
+type Projection struct {
+	Fields []string
+	Expand []string
+}
+
+// Expands reports whether relation was requested with expand=
+func (p Projection) Expands(relation string) bool {
+	return contains(p.Expand, relation)
+}
+
+// ParseProjection parses comma-separated fields= and expand= values against
+// whitelists. The id field is always included when fields are restricted.
+func ParseProjection(fields, expand string, allowedFields, allowedExpand []string) (Projection, error) {
+	var projection Projection
+
+	selected, err := parseList(fields, allowedFields, "field")
+	if err != nil {
+		return projection, err
+	}
+	if len(selected) > 0 {
+		if !contains(selected, "id") {
+			selected = append([]string{"id"}, selected...)
+		}
+		projection.Fields = selected
+	}
+
+	projection.Expand, err = parseList(expand, allowedExpand, "relation")
+	if err != nil {
+		return projection, err
+	}
+	return projection, nil
+}
+
+func parseList(value string, allowed []string, kind string) ([]string, error) {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || contains(items, part) {
+			continue
+		}
+		if !contains(allowed, part) {
+			if len(allowed) == 0 {
+				return nil, fmt.Errorf("unknown %s %q, this endpoint has none", kind, part)
+			}
+			return nil, fmt.Errorf("unknown %s %q, allowed: %s", kind, part, strings.Join(allowed, ", "))
+		}
+		items = append(items, part)
+	}
+	return items, nil
+}
+
+// marshalFields encodes value and, when fields is set, drops the selectable keys
+// that were not requested. Keys outside selectable (e.g. expanded relations or
+// search rank) are kept.
+func marshalFields(value interface{}, fields, selectable []string) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil || fields == nil {
+		return data, err
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	for _, key := range selectable {
+		if !contains(fields, key) {
+			delete(object, key)
+		}
+	}
+	return json.Marshal(object)
+}
@@ -1,18 +1,26 @@
 package dtos
 
 import (
+	"encoding/json"
+	"fmt"
 	"inventory-api/models"
 )
 
 // ToProductModel converts CreateProductInput to Product model
 func (dto *CreateProductInput) ToProductModel() *models.Product {
-	return &models.Product{
+	product := &models.Product{
 		Name:        dto.Name,
 		SKU:         dto.SKU,
 		Description: dto.Description,
 		Price:       dto.Price,
 		Quantity:    dto.Quantity,
+		Status:      models.ProductStatusActive,
+		CategoryID:  dto.CategoryID,
 	}
+	if dto.Status != "" {
+		product.Status = models.ProductStatus(dto.Status)
+	}
+	return product
 }
 
 // ToProductResponse converts Product model to ProductResponse DTO
@@ -20,16 +28,34 @@ func ToProductResponse(product *models.Product) *ProductResponse {
 	if product == nil {
 		return nil
 	}
-	return &ProductResponse{
+	response := &ProductResponse{
 		ID:          product.ID,
 		Name:        product.Name,
 		SKU:         product.SKU,
 		Description: product.Description,
 		Price:       product.Price,
 		Quantity:    product.Quantity,
+		Version:     product.Version,
 		CreatedAt:   product.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   product.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+
+	response.Status = string(product.Status)
+	if product.StatusChangedAt != nil {
+		changedAt := product.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.StatusChangedAt = &changedAt
+	}
+	response.StatusChangedBy = product.StatusChangedBy
+	response.CategoryID = product.CategoryID
+	response.ABCClass = product.ABCClass
+
+	// Mark soft-deleted products
+	if product.DeletedAt.Valid {
+		deletedAt := product.DeletedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.DeletedAt = &deletedAt
+	}
+
+	return response
 }
 
 // ToProductResponseList converts slice of Product models to slice of ProductResponse DTOs
@@ -41,6 +67,18 @@ func ToProductResponseList(products []models.Product) []ProductResponse {
 	return responses
 }
 
+// ToProductSearchResponseList converts search results to ProductResponse DTOs with rank and highlight
+func ToProductSearchResponseList(results []models.ProductSearchResult) []ProductResponse {
+	responses := make([]ProductResponse, len(results))
+	for i := range results {
+		response := ToProductResponse(&results[i].Product)
+		response.Rank = &results[i].Rank
+		response.Highlight = &results[i].Highlight
+		responses[i] = *response
+	}
+	return responses
+}
+
 // ApplyToProduct applies UpdateProductInput to existing Product model
 func (dto *UpdateProductInput) ApplyToProduct(product *models.Product) {
 	if dto.Name != nil {
@@ -52,6 +90,9 @@ func (dto *UpdateProductInput) ApplyToProduct(product *models.Product) {
 	if dto.Price != nil {
 		product.Price = *dto.Price
 	}
+	if dto.CategoryID != nil {
+		product.CategoryID = dto.CategoryID
+	}
 }
 
 // ToTransactionModel converts CreateTransactionInput to Transaction model
@@ -75,6 +116,7 @@ func ToTransactionResponse(transaction *models.Transaction) *TransactionResponse
 		ProductID:       transaction.ProductID,
 		Quantity:        transaction.Quantity,
 		TransactionType: TransactionType(transaction.TransactionType),
+		Reference:       transaction.Reference,
 		Notes:           transaction.Notes,
 		CreatedAt:       transaction.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:       transaction.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
@@ -85,6 +127,12 @@ func ToTransactionResponse(transaction *models.Transaction) *TransactionResponse
 		response.Product = ToProductResponse(&transaction.Product)
 	}
 
+	// Include acting user if loaded
+	response.CreatedBy = transaction.CreatedBy
+	if transaction.Creator != nil {
+		response.User = &UserSummary{ID: transaction.Creator.ID, Username: transaction.Creator.Username}
+	}
+
 	return response
 }
 
@@ -97,6 +145,77 @@ func ToTransactionResponseList(transactions []models.Transaction) []TransactionR
 	return responses
 }
 
+// ToTransactionExportRecord flattens a ledger row for export
+func ToTransactionExportRecord(row *models.TransactionLedgerRow) *TransactionExportRecord {
+	return &TransactionExportRecord{
+		ID:              row.Transaction.ID,
+		CreatedAt:       row.Transaction.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ProductID:       row.Transaction.ProductID,
+		ProductSKU:      row.ProductSKU,
+		ProductName:     row.ProductName,
+		TransactionType: TransactionType(row.Transaction.TransactionType),
+		Quantity:        row.Transaction.Quantity,
+		Reference:       row.Transaction.Reference,
+		Notes:           row.Transaction.Notes,
+		CreatedBy:       row.Transaction.CreatedBy,
+		CreatedByName:   row.CreatorName,
+	}
+}
+
+// ToProductImageResponse converts ProductImage model to ProductImageResponse DTO
+func ToProductImageResponse(image *models.ProductImage) *ProductImageResponse {
+	if image == nil {
+		return nil
+	}
+	url := fmt.Sprintf("/products/%d/images/%d", image.ProductID, image.ID)
+	return &ProductImageResponse{
+		ID:           image.ID,
+		ProductID:    image.ProductID,
+		FileName:     image.FileName,
+		ContentType:  image.ContentType,
+		Size:         image.Size,
+		URL:          url,
+		ThumbnailURL: url + "/thumbnail",
+		UploadedBy:   image.UploadedBy,
+		CreatedAt:    image.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// ToProductImageResponseList converts slice of ProductImage models to slice of ProductImageResponse DTOs
+func ToProductImageResponseList(images []models.ProductImage) []ProductImageResponse {
+	responses := make([]ProductImageResponse, len(images))
+	for i, image := range images {
+		responses[i] = *ToProductImageResponse(&image)
+	}
+	return responses
+}
+
+// ToAttachmentResponse converts TransactionAttachment model to AttachmentResponse DTO
+func ToAttachmentResponse(attachment *models.TransactionAttachment) *AttachmentResponse {
+	if attachment == nil {
+		return nil
+	}
+	return &AttachmentResponse{
+		ID:            attachment.ID,
+		TransactionID: attachment.TransactionID,
+		FileName:      attachment.FileName,
+		ContentType:   attachment.ContentType,
+		Size:          attachment.Size,
+		URL:           fmt.Sprintf("/transactions/%d/attachments/%d", attachment.TransactionID, attachment.ID),
+		UploadedBy:    attachment.UploadedBy,
+		CreatedAt:     attachment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// ToAttachmentResponseList converts slice of TransactionAttachment models to slice of AttachmentResponse DTOs
+func ToAttachmentResponseList(attachments []models.TransactionAttachment) []AttachmentResponse {
+	responses := make([]AttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		responses[i] = *ToAttachmentResponse(&attachment)
+	}
+	return responses
+}
+
 // ToUserResponse converts User model to UserResponse DTO
 func ToUserResponse(user *models.User) *UserResponse {
 	if user == nil {
@@ -108,6 +227,7 @@ func ToUserResponse(user *models.User) *UserResponse {
 		Email:    user.Email,
 		Phone:    user.Phone,
 		Role:     user.Role,
+		Version:  user.Version,
 	}
 }
 
@@ -119,3 +239,150 @@ func ToUserResponseList(users []models.User) []UserResponse {
 	}
 	return responses
 }
+
+// ToUserMovementSummaryList converts per-user movement totals to DTOs
+func ToUserMovementSummaryList(movements []models.UserMovement) []UserMovementSummary {
+	responses := make([]UserMovementSummary, len(movements))
+	for i, movement := range movements {
+		responses[i] = UserMovementSummary{
+			UserID:      movement.UserID,
+			Username:    movement.Username,
+			InCount:     movement.InCount,
+			InQuantity:  movement.InQuantity,
+			OutCount:    movement.OutCount,
+			OutQuantity: movement.OutQuantity,
+			LastMovedAt: movement.LastMovedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+	return responses
+}
+
+// ToProductRevisionResponse converts a ProductRevision model to its DTO
+func ToProductRevisionResponse(revision *models.ProductRevision) ProductRevisionResponse {
+	return ProductRevisionResponse{
+		Revision:       revision.Revision,
+		ProductVersion: revision.ProductVersion,
+		ProductRevisionFields: ProductRevisionFields{
+			Name:        revision.Name,
+			SKU:         revision.SKU,
+			Description: revision.Description,
+			Price:       revision.Price,
+			Quantity:    revision.Quantity,
+			Status:      string(revision.Status),
+			CategoryID:  revision.CategoryID,
+		},
+		ChangedBy: revision.ChangedBy,
+		CreatedAt: revision.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func ToProductRevisionResponseList(revisions []models.ProductRevision) []ProductRevisionResponse {
+	responses := make([]ProductRevisionResponse, len(revisions))
+	for i := range revisions {
+		responses[i] = ToProductRevisionResponse(&revisions[i])
+	}
+	return responses
+}
+
+// ToRollbackInput builds the update that restores the revision's catalogue fields.
+// Quantity is left out because stock levels are maintained by transactions.
+func ToRollbackInput(revision *models.ProductRevision) *UpdateProductInput {
+	return &UpdateProductInput{
+		Name:        &revision.Name,
+		Description: &revision.Description,
+		Price:       &revision.Price,
+		CategoryID:  revision.CategoryID,
+	}
+}
+
+func ToCategoryResponse(category *models.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:        category.ID,
+		Name:      category.Name,
+		CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func ToCategoryResponseList(categories []models.Category) []CategoryResponse {
+	responses := make([]CategoryResponse, len(categories))
+	for i := range categories {
+		responses[i] = ToCategoryResponse(&categories[i])
+	}
+	return responses
+}
+
+// ToAuditLogResponse converts an AuditLog model to its DTO
+func ToAuditLogResponse(entry *models.AuditLog) AuditLogResponse {
+	response := AuditLogResponse{
+		ID:        entry.ID,
+		ActorID:   entry.ActorID,
+		ActorName: entry.ActorName,
+		Entity:    entry.Entity,
+		EntityID:  entry.EntityID,
+		Action:    entry.Action,
+		RequestID: entry.RequestID,
+		IP:        entry.IP,
+		Hash:      entry.Hash,
+		PrevHash:  entry.PrevHash,
+		CreatedAt: entry.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if entry.Before != nil {
+		response.Before = json.RawMessage(*entry.Before)
+	}
+	if entry.After != nil {
+		response.After = json.RawMessage(*entry.After)
+	}
+	if entry.Changes != nil {
+		response.Changes = json.RawMessage(*entry.Changes)
+	}
+	return response
+}
+
+// ToAuditLogResponseList converts a slice of AuditLog models to DTOs
+func ToAuditLogResponseList(entries []models.AuditLog) []AuditLogResponse {
+	responses := make([]AuditLogResponse, len(entries))
+	for i := range entries {
+		responses[i] = ToAuditLogResponse(&entries[i])
+	}
+	return responses
+}
+
+// ToJobResponse converts Job model to JobResponse DTO
+func ToJobResponse(job *models.Job) JobResponse {
+	response := JobResponse{
+		ID:              job.ID,
+		Type:            job.Type,
+		Status:          string(job.Status),
+		Progress:        job.Progress,
+		Attempts:        job.Attempts,
+		MaxAttempts:     job.MaxAttempts,
+		Error:           job.Error,
+		ResultName:      job.ResultName,
+		ResultSize:      job.ResultSize,
+		CreatedBy:       job.CreatedBy,
+		CancelRequested: job.CancelRequested && !job.IsFinished(),
+		CreatedAt:       job.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       job.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if job.Result != nil {
+		response.Result = json.RawMessage(*job.Result)
+	}
+	if job.StartedAt != nil {
+		startedAt := job.StartedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.StartedAt = &startedAt
+	}
+	if job.FinishedAt != nil {
+		finishedAt := job.FinishedAt.Format("2006-01-02T15:04:05Z07:00")
+		response.FinishedAt = &finishedAt
+	}
+	return response
+}
+
+// ToJobResponseList converts slice of Job models to slice of JobResponse DTOs
+func ToJobResponseList(jobs []models.Job) []JobResponse {
+	responses := make([]JobResponse, len(jobs))
+	for i := range jobs {
+		responses[i] = ToJobResponse(&jobs[i])
+	}
+	return responses
+}
@@ -0,0 +1,110 @@
+package dtos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// OptionalParam is a query parameter that distinguishes "not sent" from the zero value,
+// e.g. max_qty=0 to find out-of-stock products
+type OptionalParam[T any] struct {
+	Value T
+	IsSet bool
+}
+
+// Schema returns the schema of the wrapped type
+func (o OptionalParam[T]) Schema(r huma.Registry) *huma.Schema {
+	return huma.SchemaFromType(r, reflect.TypeOf(o.Value))
+}
+
+// Receiver lets huma parse the parameter into Value
+func (o *OptionalParam[T]) Receiver() reflect.Value {
+	return reflect.ValueOf(o).Elem().Field(0)
+}
+
+// OnParamSet records whether the parameter was present in the request
+func (o *OptionalParam[T]) OnParamSet(isSet bool, parsed any) {
+	o.IsSet = isSet
+}
+
+// Ptr returns a pointer to the value, or nil if the parameter was not sent
+func (o OptionalParam[T]) Ptr() *T {
+	if !o.IsSet {
+		return nil
+	}
+	return &o.Value
+}
+
+// PageRequest selects a page either by offset or by an opaque cursor
+type PageRequest struct {
+	Limit     int
+	Offset    int
+	Cursor    string
+	SkipCount bool
+}
+
+// PageInfo describes where the returned page sits in the full result.
+// Total is nil when counting was skipped.
+type PageInfo struct {
+	NextCursor string
+	HasMore    bool
+	Total      *int64
+}
+
+// SortField is one column of a sort expression
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sortable fields for list endpoints
+var (
+	ProductSortFields     = []string{"id", "name", "sku", "price", "quantity", "created_at", "updated_at"}
+	TransactionSortFields = []string{"id", "product_id", "quantity", "transaction_type", "created_at"}
+)
+
+// ParseSort parses a comma-separated sort expression such as "-price,name".
+// A leading "-" sorts descending. Only fields in allowed are accepted.
+func ParseSort(sort string, allowed []string) ([]SortField, error) {
+	if strings.TrimSpace(sort) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		} else if strings.HasPrefix(part, "+") {
+			field.Field = part[1:]
+		}
+
+		if !contains(allowed, field.Field) {
+			return nil, fmt.Errorf("cannot sort by %q, allowed fields: %s", field.Field, strings.Join(allowed, ", "))
+		}
+		if seen[field.Field] {
+			continue
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
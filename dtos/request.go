@@ -1,16 +1,136 @@
 package dtos
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
 type CreateProductRequest struct {
-	Body CreateProductInput
+	IdempotencyKey string `header:"Idempotency-Key" maxLength:"255" doc:"Unique key to safely retry the request"`
+	Body           CreateProductInput
+}
+
+type CreateCategoryRequest struct {
+	Body CreateCategoryInput
 }
 
 type UpdateProductRequest struct {
-	ID   uint `path:"id"`
-	Body UpdateProductInput
+	ID      uint   `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the product version being updated"`
+	Body    UpdateProductInput
+}
+
+type ChangeProductStatusRequest struct {
+	ID      uint   `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the product version being changed"`
+	Body    ChangeProductStatusInput
+}
+
+type BulkProductRequest struct {
+	DryRun bool `query:"dry_run" doc:"Only preview the affected products without saving"`
+	Body   BulkProductInput
 }
 
 type CreateTransactionRequest struct {
-	Body CreateTransactionInput
+	IdempotencyKey string `header:"Idempotency-Key" maxLength:"255" doc:"Unique key to safely retry the request"`
+	Body           CreateTransactionInput
+}
+
+type CreateTransactionBatchRequest struct {
+	IdempotencyKey string `header:"Idempotency-Key" maxLength:"255" doc:"Unique key to safely retry the request"`
+	Body           CreateTransactionBatchInput
+}
+
+// File upload form with a single "file" field
+type FileUploadForm struct {
+	File huma.FormFile `form:"file" required:"true" doc:"File to upload"`
+}
+
+type UploadFileRequest struct {
+	ID      uint `path:"id"`
+	RawBody huma.MultipartFormFiles[FileUploadForm]
+}
+
+type ImportProductsForm struct {
+	File    huma.FormFile `form:"file" required:"true" doc:"CSV or XLSX file with a header row"`
+	Format  string        `form:"format" enum:"csv,xlsx" doc:"File format, detected from the file name or content when omitted"`
+	Sheet   string        `form:"sheet" doc:"XLSX worksheet to import (default: the active sheet)"`
+	Mapping string        `form:"mapping" doc:"JSON object mapping product fields (sku, name, description, price, quantity, status, category) to column headers, e.g. {\"sku\":\"Item Code\"}"`
+}
+
+type ImportProductsRequest struct {
+	DryRun  bool `query:"dry_run" doc:"Only validate the file and return the report without saving"`
+	RawBody huma.MultipartFormFiles[ImportProductsForm]
+}
+
+// Options returns the import options with the column mapping decoded
+func (r *ImportProductsRequest) Options() (ImportOptions, error) {
+	form := r.RawBody.Data()
+	options := ImportOptions{Format: form.Format, Sheet: form.Sheet, DryRun: r.DryRun}
+	if strings.TrimSpace(form.Mapping) != "" {
+		if err := json.Unmarshal([]byte(form.Mapping), &options.Mapping); err != nil {
+			return ImportOptions{}, fmt.Errorf("invalid mapping: %w", err)
+		}
+	}
+	return options, nil
+}
+
+type CreateJobInput struct {
+	Type   string          `json:"type" enum:"product_export,transaction_export,user_movement_report,product_forecast" doc:"Job type; product imports are submitted with POST /jobs/product-import"`
+	Params json.RawMessage `json:"params,omitempty" doc:"Job parameters: {format, filter} for exports, {filter} for reports, {product_ids} for forecasts (admin only). Filters use the same names as the list query parameters."`
+}
+
+// DecodeParams decodes the job parameters into v, rejecting unknown fields
+func (in *CreateJobInput) DecodeParams(v interface{}) error {
+	if len(in.Params) == 0 || string(in.Params) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(in.Params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+type CreateJobRequest struct {
+	Body CreateJobInput
+}
+
+type JobListQuery struct {
+	Status string `query:"status" enum:"queued,running,succeeded,failed,cancelled" doc:"Filter by status"`
+	Type   string `query:"type" doc:"Filter by job type"`
+	UserID uint   `query:"user_id" doc:"Filter by the user who submitted the job (admins only, others always see their own)"`
+	PaginationQuery
+}
+
+func (q *JobListQuery) ToJobFilter() *JobFilter {
+	filter := &JobFilter{}
+	if q.Status != "" {
+		filter.Status = &q.Status
+	}
+	if q.Type != "" {
+		filter.Type = &q.Type
+	}
+	if q.UserID != 0 {
+		filter.CreatedBy = &q.UserID
+	}
+	return filter
+}
+
+type ProductImageParam struct {
+	ID      uint `path:"id"`
+	ImageID uint `path:"imageId"`
+}
+
+type AttachmentParam struct {
+	ID           uint `path:"id"`
+	AttachmentID uint `path:"attachmentId"`
 }
 
 type IDParam struct {
@@ -18,23 +138,86 @@ type IDParam struct {
 }
 
 type PaginationQuery struct {
-	Limit  int `query:"limit" default:"10" minimum:"1" maximum:"100"`
-	Offset int `query:"offset" default:"0" minimum:"0"`
+	Limit     int    `query:"limit" default:"10" minimum:"1" maximum:"100"`
+	Offset    int    `query:"offset" default:"0" minimum:"0"`
+	Cursor    string `query:"cursor" doc:"Opaque cursor from next_cursor of the previous page; cannot be combined with offset"`
+	SkipCount bool   `query:"skip_count" doc:"Do not compute total, which can be slow on very large tables"`
+}
+
+func (q *PaginationQuery) Page() PageRequest {
+	return PageRequest{Limit: q.Limit, Offset: q.Offset, Cursor: q.Cursor, SkipCount: q.SkipCount}
+}
+
+// ProductFieldsQuery selects which product fields a read endpoint returns
+type ProductFieldsQuery struct {
+	Fields string `query:"fields" doc:"Comma-separated fields to return, e.g. id,name,quantity. Allowed: id, name, sku, description, price, quantity, version, status, status_changed_at, status_changed_by, category_id, abc_class, created_at, updated_at, deleted_at"`
+}
+
+func (q *ProductFieldsQuery) Projection() (Projection, error) {
+	return ParseProjection(q.Fields, "", ProductFields, nil)
+}
+
+// TransactionFieldsQuery selects which transaction fields and relations a read endpoint returns
+type TransactionFieldsQuery struct {
+	Fields string `query:"fields" doc:"Comma-separated fields to return, e.g. id,product_id,quantity. Allowed: id, product_id, quantity, transaction_type, reference, notes, created_by, created_at, updated_at"`
+	Expand string `query:"expand" doc:"Comma-separated relations to embed. Allowed: product, user"`
+}
+
+func (q *TransactionFieldsQuery) Projection() (Projection, error) {
+	return ParseProjection(q.Fields, q.Expand, TransactionFields, TransactionExpansions)
+}
+
+type ProductDetailQuery struct {
+	IDParam
+	ProductFieldsQuery
+}
+
+type TransactionDetailQuery struct {
+	IDParam
+	TransactionFieldsQuery
+}
+
+// ProductFilterQuery holds the product filters shared by listing and export
+type ProductFilterQuery struct {
+	Q          string  `query:"q" maxLength:"200" doc:"Full-text search over name, SKU and description, ordered by relevance"`
+	SKU        string  `query:"sku" doc:"Filter by SKU (exact match)"`
+	Name       string  `query:"name" doc:"Filter by name (partial match)"`
+	MinPrice   float64 `query:"min_price" doc:"Filter by minimum price"`
+	MaxPrice   float64 `query:"max_price" doc:"Filter by maximum price"`
+	CategoryID uint    `query:"category_id" doc:"Filter by category ID"`
+	ABCClass   string  `query:"abc_class" enum:"A,B,C" doc:"Filter by the class from the last stored ABC analysis"`
+
+	Status         string `query:"status" default:"active" enum:"draft,active,discontinued,archived,all" doc:"Filter by lifecycle status, or all"`
+	IncludeDeleted bool   `query:"include_deleted" doc:"Include soft-deleted products (admin only)"`
+
+	CreatedFrom time.Time          `query:"created_from" doc:"Only products created at or after this time (RFC 3339)"`
+	CreatedTo   time.Time          `query:"created_to" doc:"Only products created at or before this time (RFC 3339)"`
+	MinQty      OptionalParam[int] `query:"min_qty" doc:"Filter by minimum quantity"`
+	MaxQty      OptionalParam[int] `query:"max_qty" doc:"Filter by maximum quantity"`
 }
 
 type ProductListQuery struct {
-	SKU      string  `query:"sku" doc:"Filter by SKU (exact match)"`
-	Name     string  `query:"name" doc:"Filter by name (partial match)"`
-	MinPrice float64 `query:"min_price" doc:"Filter by minimum price"`
-	MaxPrice float64 `query:"max_price" doc:"Filter by maximum price"`
-	Limit    int     `query:"limit" default:"10" minimum:"1" maximum:"100"`
-	Offset   int     `query:"offset" default:"0" minimum:"0"`
+	ProductFieldsQuery
+	ProductFilterQuery
+
+	Limit     int    `query:"limit" default:"10" minimum:"1" maximum:"100"`
+	Offset    int    `query:"offset" default:"0" minimum:"0"`
+	Cursor    string `query:"cursor" doc:"Opaque cursor from next_cursor of the previous page; cannot be combined with offset or relevance-ranked search"`
+	SkipCount bool   `query:"skip_count" doc:"Do not compute total, which can be slow on very large tables"`
+	Sort      string `query:"sort" doc:"Comma-separated sort fields, prefix with - for descending (e.g. -price,name). Allowed: id, name, sku, price, quantity, created_at, updated_at"`
+}
+
+func (q *ProductListQuery) Page() PageRequest {
+	return PageRequest{Limit: q.Limit, Offset: q.Offset, Cursor: q.Cursor, SkipCount: q.SkipCount}
 }
 
-func (q *ProductListQuery) ToProductFilter() *ProductFilter {
+func (q *ProductFilterQuery) ToProductFilter() *ProductFilter {
 	filter := &ProductFilter{}
 
 	// Only set pointer if value is not empty/zero
+	if q.Q != "" {
+		filter.Query = &q.Q
+	}
 	if q.SKU != "" {
 		filter.SKU = &q.SKU
 	}
@@ -47,13 +230,261 @@ func (q *ProductListQuery) ToProductFilter() *ProductFilter {
 	if q.MaxPrice > 0 {
 		filter.MaxPrice = &q.MaxPrice
 	}
+	if q.Status != "" && q.Status != "all" {
+		filter.Status = &q.Status
+	}
+	if !q.CreatedFrom.IsZero() {
+		filter.CreatedFrom = &q.CreatedFrom
+	}
+	if !q.CreatedTo.IsZero() {
+		filter.CreatedTo = &q.CreatedTo
+	}
+	if q.CategoryID != 0 {
+		filter.CategoryID = &q.CategoryID
+	}
+	if q.ABCClass != "" {
+		filter.ABCClass = &q.ABCClass
+	}
+	filter.MinQty = q.MinQty.Ptr()
+	filter.MaxQty = q.MaxQty.Ptr()
+	filter.IncludeDeleted = q.IncludeDeleted
 
 	return filter
 }
 
+// TransactionFilterQuery holds the transaction filters shared by listing and export
+type TransactionFilterQuery struct {
+	Type      string    `query:"type" enum:"IN,OUT,ADJUST" doc:"Filter by transaction type; ADJUST rows record quantity changes made outside movements and are only listed when asked for"`
+	ProductID uint      `query:"product_id" doc:"Filter by product ID"`
+	UserID    uint      `query:"user_id" doc:"Filter by the user who recorded the movement"`
+	From      time.Time `query:"from" doc:"Only transactions created at or after this time (RFC 3339)"`
+	To        time.Time `query:"to" doc:"Only transactions created at or before this time (RFC 3339)"`
+	Notes     string    `query:"notes" doc:"Search in notes (partial match)"`
+}
+
+type TransactionListQuery struct {
+	TransactionFieldsQuery
+	TransactionFilterQuery
+
+	Sort      string `query:"sort" default:"-created_at" doc:"Comma-separated sort fields, prefix with - for descending. Allowed: id, product_id, quantity, transaction_type, created_at"`
+	Limit     int    `query:"limit" default:"10" minimum:"1" maximum:"100"`
+	Offset    int    `query:"offset" default:"0" minimum:"0"`
+	Cursor    string `query:"cursor" doc:"Opaque cursor from next_cursor of the previous page; cannot be combined with offset"`
+	SkipCount bool   `query:"skip_count" doc:"Do not compute total, which can be slow on very large tables"`
+}
+
+func (q *TransactionListQuery) Page() PageRequest {
+	return PageRequest{Limit: q.Limit, Offset: q.Offset, Cursor: q.Cursor, SkipCount: q.SkipCount}
+}
+
+func (q *TransactionFilterQuery) ToTransactionFilter() *TransactionFilter {
+	filter := &TransactionFilter{}
+
+	// Only set pointer if value is not empty/zero
+	if q.Type != "" {
+		filter.Type = &q.Type
+	}
+	if q.ProductID != 0 {
+		filter.ProductID = &q.ProductID
+	}
+	if q.UserID != 0 {
+		filter.UserID = &q.UserID
+	}
+	if !q.From.IsZero() {
+		filter.From = &q.From
+	}
+	if !q.To.IsZero() {
+		filter.To = &q.To
+	}
+	if q.Notes != "" {
+		filter.Notes = &q.Notes
+	}
+
+	return filter
+}
+
+// UserMovementReportQuery filters the per-user movement report
+type UserMovementReportQuery struct {
+	From      time.Time `query:"from" doc:"Only movements at or after this time (RFC 3339)"`
+	To        time.Time `query:"to" doc:"Only movements at or before this time (RFC 3339)"`
+	ProductID uint      `query:"product_id" doc:"Only movements of this product"`
+	UserID    uint      `query:"user_id" doc:"Only movements recorded by this user (admins only, others always see their own)"`
+}
+
+func (q *UserMovementReportQuery) ToTransactionFilter() *TransactionFilter {
+	filter := &TransactionFilter{}
+	if !q.From.IsZero() {
+		filter.From = &q.From
+	}
+	if !q.To.IsZero() {
+		filter.To = &q.To
+	}
+	if q.ProductID != 0 {
+		filter.ProductID = &q.ProductID
+	}
+	if q.UserID != 0 {
+		filter.UserID = &q.UserID
+	}
+	return filter
+}
+
+// MovementReportQuery selects the stock movement summary
+type MovementReportQuery struct {
+	From       time.Time `query:"from" required:"true" doc:"Start of the range, inclusive (RFC 3339)"`
+	To         time.Time `query:"to" required:"true" doc:"End of the range, exclusive (RFC 3339)"`
+	GroupBy    string    `query:"group_by" default:"day" enum:"day,week,month" doc:"Period length; periods are in UTC and weeks start on Monday"`
+	ProductID  uint      `query:"product_id" doc:"Only this product"`
+	CategoryID uint      `query:"category_id" doc:"Only products in this category"`
+}
+
+func (q *MovementReportQuery) ToMovementFilter() *MovementFilter {
+	filter := &MovementFilter{From: q.From, To: q.To, GroupBy: q.GroupBy}
+	if q.ProductID != 0 {
+		filter.ProductID = &q.ProductID
+	}
+	if q.CategoryID != 0 {
+		filter.CategoryID = &q.CategoryID
+	}
+	return filter
+}
+
+type MovementReportExportQuery struct {
+	MovementReportQuery
+	Format string `query:"format" default:"csv" enum:"csv,xlsx" doc:"File format"`
+}
+
+// ProductTimeseriesQuery selects the range and buckets of a product's stock level series
+type ProductTimeseriesQuery struct {
+	ID       uint      `path:"id"`
+	From     time.Time `query:"from" required:"true" doc:"Start of the range, inclusive (RFC 3339)"`
+	To       time.Time `query:"to" required:"true" doc:"End of the range, exclusive (RFC 3339)"`
+	Interval string    `query:"interval" default:"day" enum:"day,week,month" doc:"Bucket length; buckets are in UTC and weeks start on Monday"`
+}
+
+// ProductForecastQuery selects how many days of a product's demand forecast to return
+type ProductForecastQuery struct {
+	ID      uint `path:"id"`
+	Horizon int  `query:"horizon" default:"30" minimum:"1" maximum:"90" doc:"Days to forecast, starting today (UTC)"`
+}
+
+// ABCReportQuery sets the window and thresholds of an ABC analysis
+type ABCReportQuery struct {
+	WindowDays int     `query:"window_days" minimum:"0" maximum:"3650" doc:"Days of OUT movements to rank by (default ABC_WINDOW_DAYS)"`
+	AThreshold float64 `query:"a_threshold" minimum:"0" maximum:"100" doc:"Cumulative share of the consumption value covered by class A, in percent (default ABC_THRESHOLD_A)"`
+	BThreshold float64 `query:"b_threshold" minimum:"0" maximum:"100" doc:"Cumulative share covered by classes A and B together, in percent (default ABC_THRESHOLD_B)"`
+}
+
+func (q *ABCReportQuery) Options() ABCOptions {
+	return ABCOptions{WindowDays: q.WindowDays, AThreshold: q.AThreshold, BThreshold: q.BThreshold}
+}
+
+// AgingReportQuery selects the dead stock and aging report
+type AgingReportQuery struct {
+	Days          int    `query:"days" default:"90" minimum:"1" maximum:"3650" doc:"Products without an OUT movement in this many days are dead stock"`
+	CategoryID    uint   `query:"category_id" doc:"Only products in this category"`
+	ABCClass      string `query:"abc_class" enum:"A,B,C" doc:"Only products of this ABC class"`
+	IncludeMoving bool   `query:"include_moving" doc:"Also list products that moved out within the days, for an aging view of all stock"`
+	Sort          string `query:"sort" default:"-value" enum:"value,-value,idle_days,-idle_days,quantity,-quantity,sku,-sku" doc:"Sort field, prefix with - for descending"`
+}
+
+func (q *AgingReportQuery) Options() *AgingOptions {
+	options := &AgingOptions{Days: q.Days, IncludeMoving: q.IncludeMoving, Sort: q.Sort}
+	if q.CategoryID != 0 {
+		options.CategoryID = &q.CategoryID
+	}
+	if q.ABCClass != "" {
+		options.ABCClass = &q.ABCClass
+	}
+	return options
+}
+
+type AgingReportExportQuery struct {
+	AgingReportQuery
+	Format string `query:"format" default:"csv" enum:"csv,xlsx" doc:"File format"`
+}
+
+// DashboardQuery sets the window of the KPI dashboard
+type DashboardQuery struct {
+	Days int `query:"days" default:"30" minimum:"1" maximum:"365" doc:"Days of movements, including today, used for turnover, top movers and the trend"`
+	Top  int `query:"top" default:"5" minimum:"1" maximum:"50" doc:"Number of top movers"`
+}
+
+// AuditFilterQuery filters audit log entries
+type AuditFilterQuery struct {
+	ActorID   uint      `query:"actor_id" doc:"Filter by the user who made the change"`
+	Entity    string    `query:"entity" enum:"product,product_image,transaction,transaction_attachment,user,category" doc:"Filter by entity type"`
+	EntityID  uint      `query:"entity_id" doc:"Filter by entity ID"`
+	Action    string    `query:"action" doc:"Filter by action, e.g. create, update, delete"`
+	RequestID string    `query:"request_id" doc:"Filter by request ID"`
+	From      time.Time `query:"from" doc:"Only entries at or after this time (RFC 3339)"`
+	To        time.Time `query:"to" doc:"Only entries at or before this time (RFC 3339)"`
+}
+
+func (q *AuditFilterQuery) ToAuditFilter() *AuditFilter {
+	filter := &AuditFilter{}
+	if q.ActorID != 0 {
+		filter.ActorID = &q.ActorID
+	}
+	if q.Entity != "" {
+		filter.Entity = &q.Entity
+	}
+	if q.EntityID != 0 {
+		filter.EntityID = &q.EntityID
+	}
+	if q.Action != "" {
+		filter.Action = &q.Action
+	}
+	if q.RequestID != "" {
+		filter.RequestID = &q.RequestID
+	}
+	if !q.From.IsZero() {
+		filter.From = &q.From
+	}
+	if !q.To.IsZero() {
+		filter.To = &q.To
+	}
+	return filter
+}
+
+type AuditListQuery struct {
+	AuditFilterQuery
+	PaginationQuery
+}
+
+type ProductExportQuery struct {
+	ProductFilterQuery
+	Format string `query:"format" default:"csv" enum:"csv,xlsx,ndjson" doc:"Export format"`
+}
+
+type TransactionExportQuery struct {
+	TransactionFilterQuery
+	Format string `query:"format" default:"csv" enum:"csv,xlsx,ndjson" doc:"Export format"`
+}
+
+type AuditExportQuery struct {
+	AuditFilterQuery
+	Format string `query:"format" default:"csv" enum:"csv,ndjson" doc:"Export format"`
+}
+
+type ProductRevisionsQuery struct {
+	IDParam
+	PaginationQuery
+}
+
+type ProductRevisionParam struct {
+	ID       uint `path:"id"`
+	Revision uint `path:"n" minimum:"1" doc:"Revision number"`
+}
+
+type RollbackProductRequest struct {
+	ProductRevisionParam
+	IfMatch string `header:"If-Match" doc:"ETag of the product version being rolled back"`
+}
+
 type ProductTransactionsQuery struct {
 	IDParam
 	PaginationQuery
+	TransactionFieldsQuery
 }
 
 // User requests
@@ -66,8 +497,9 @@ type LoginRequest struct {
 }
 
 type UpdateUserRequest struct {
-	ID   uint `path:"id"`
-	Body UpdateUserInput
+	ID      uint   `path:"id"`
+	IfMatch string `header:"If-Match" doc:"ETag of the user version being updated"`
+	Body    UpdateUserInput
 }
 
 type ChangePasswordRequest struct {
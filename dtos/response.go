@@ -1,14 +1,76 @@
 package dtos
 
 type SingleProductResponse struct {
+	ETag string `header:"ETag"`
 	Body *ProductResponse
 }
 
 type ProductListResponse struct {
+	Link string `header:"Link"`
 	Body struct {
-		Products []ProductResponse `json:"products"`
-		Limit    int               `json:"limit"`
-		Offset   int               `json:"offset"`
+		Products   []ProductResponse `json:"products"`
+		Limit      int               `json:"limit"`
+		Offset     int               `json:"offset"`
+		Total      *int64            `json:"total,omitempty"`
+		HasMore    bool              `json:"has_more"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+}
+
+type ProductRevisionListResponse struct {
+	Link string `header:"Link"`
+	Body struct {
+		Revisions  []ProductRevisionResponse `json:"revisions"`
+		Limit      int                       `json:"limit"`
+		Offset     int                       `json:"offset"`
+		Total      *int64                    `json:"total,omitempty"`
+		HasMore    bool                      `json:"has_more"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}
+}
+
+type ProductRevisionDiffResponse struct {
+	Body *ProductRevisionDiff
+}
+
+type SingleCategoryResponse struct {
+	Body *CategoryResponse
+}
+
+type CategoryListResponse struct {
+	Body struct {
+		Categories []CategoryResponse `json:"categories"`
+	}
+}
+
+type SingleJobResponse struct {
+	Location string `header:"Location"`
+	Body     *JobResponse
+}
+
+type JobListResponse struct {
+	Link string `header:"Link"`
+	Body struct {
+		Jobs       []JobResponse `json:"jobs"`
+		Limit      int           `json:"limit"`
+		Offset     int           `json:"offset"`
+		Total      *int64        `json:"total,omitempty"`
+		HasMore    bool          `json:"has_more"`
+		NextCursor string        `json:"next_cursor,omitempty"`
+	}
+}
+
+type BulkProductResponse struct {
+	Body *BulkProductReport
+}
+
+type ImportProductsResponse struct {
+	Body *ImportReport
+}
+
+type PurgeProductsResponse struct {
+	Body struct {
+		Purged int64 `json:"purged"`
 	}
 }
 
@@ -16,11 +78,91 @@ type SingleTransactionResponse struct {
 	Body *TransactionResponse
 }
 
+type TransactionBatchResult struct {
+	Body *TransactionBatchResponse
+}
+
 type TransactionListResponse struct {
+	Link string `header:"Link"`
 	Body struct {
 		Transactions []TransactionResponse `json:"transactions"`
 		Limit        int                   `json:"limit"`
 		Offset       int                   `json:"offset"`
+		Total        *int64                `json:"total,omitempty"`
+		HasMore      bool                  `json:"has_more"`
+		NextCursor   string                `json:"next_cursor,omitempty"`
+	}
+}
+
+type UserMovementReportResponse struct {
+	Body struct {
+		Users []UserMovementSummary `json:"users"`
+	}
+}
+
+type MovementReportResponse struct {
+	Body struct {
+		From    string                   `json:"from"`
+		To      string                   `json:"to"`
+		GroupBy string                   `json:"group_by"`
+		Rows    []ProductMovementSummary `json:"rows"`
+	}
+}
+
+type ABCReportResponse struct {
+	Body *ABCReport
+}
+
+type ProductForecastResponse struct {
+	Body *ProductForecast
+}
+
+type StockTimeseriesResponse struct {
+	Body *StockTimeseries
+}
+
+type AgingReportResponse struct {
+	Body *AgingReport
+}
+
+type DashboardResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         *Dashboard
+}
+
+type AuditLogListResponse struct {
+	Link string `header:"Link"`
+	Body struct {
+		Entries    []AuditLogResponse `json:"entries"`
+		Limit      int                `json:"limit"`
+		Offset     int                `json:"offset"`
+		Total      *int64             `json:"total,omitempty"`
+		HasMore    bool               `json:"has_more"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}
+}
+
+type AuditVerifyResult struct {
+	Body *AuditVerifyResponse
+}
+
+type SingleProductImageResponse struct {
+	Body *ProductImageResponse
+}
+
+type ProductImageListResponse struct {
+	Body struct {
+		Images []ProductImageResponse `json:"images"`
+	}
+}
+
+type SingleAttachmentResponse struct {
+	Body *AttachmentResponse
+}
+
+type AttachmentListResponse struct {
+	Body struct {
+		Attachments []AttachmentResponse `json:"attachments"`
 	}
 }
 
@@ -35,13 +177,18 @@ type LoginResponse struct {
 }
 
 type SingleUserResponse struct {
+	ETag string `header:"ETag"`
 	Body *UserResponse
 }
 
 type UserListResponse struct {
+	Link string `header:"Link"`
 	Body struct {
-		Users  []UserResponse `json:"users"`
-		Limit  int            `json:"limit"`
-		Offset int            `json:"offset"`
+		Users      []UserResponse `json:"users"`
+		Limit      int            `json:"limit"`
+		Offset     int            `json:"offset"`
+		Total      *int64         `json:"total,omitempty"`
+		HasMore    bool           `json:"has_more"`
+		NextCursor string         `json:"next_cursor,omitempty"`
 	}
 }
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// multipartOverhead is extra body size allowed on top of the file for form encoding
+const multipartOverhead = 64 * 1024
+
+type AttachmentHandler struct {
+	service *services.AttachmentService
+}
+
+func NewAttachmentHandler(service *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+func (h *AttachmentHandler) RegisterRoutes(api huma.API) {
+	// Product image routes - uploads require authentication, downloads are public like product details
+	huma.Register(api, huma.Operation{
+		OperationID:  "upload-product-image",
+		Method:       http.MethodPost,
+		Path:         "/products/{id}/images",
+		Summary:      "Upload a product image",
+		Tags:         []string{"Files"},
+		MaxBodyBytes: h.service.MaxImageSize() + multipartOverhead,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.UploadProductImage)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-product-images",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/images",
+		Summary:     "List product images",
+		Tags:        []string{"Files"},
+	}, h.ListProductImages)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "download-product-image",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/images/{imageId}",
+		Summary:     "Download a product image",
+		Tags:        []string{"Files"},
+	}, h.DownloadProductImage)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "download-product-thumbnail",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/images/{imageId}/thumbnail",
+		Summary:     "Download a product image thumbnail",
+		Tags:        []string{"Files"},
+	}, h.DownloadProductThumbnail)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-product-image",
+		Method:      http.MethodDelete,
+		Path:        "/products/{id}/images/{imageId}",
+		Summary:     "Delete a product image (admin only)",
+		Tags:        []string{"Files"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.DeleteProductImage)
+
+	// Transaction attachment routes - require authentication
+	huma.Register(api, huma.Operation{
+		OperationID:  "upload-transaction-attachment",
+		Method:       http.MethodPost,
+		Path:         "/transactions/{id}/attachments",
+		Summary:      "Attach a file to a transaction",
+		Tags:         []string{"Files"},
+		MaxBodyBytes: h.service.MaxAttachmentSize() + multipartOverhead,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.UploadTransactionAttachment)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-transaction-attachments",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/attachments",
+		Summary:     "List transaction attachments",
+		Tags:        []string{"Files"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ListTransactionAttachments)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "download-transaction-attachment",
+		Method:      http.MethodGet,
+		Path:        "/transactions/{id}/attachments/{attachmentId}",
+		Summary:     "Download a transaction attachment",
+		Tags:        []string{"Files"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.DownloadTransactionAttachment)
+}
+
+func (h *AttachmentHandler) UploadProductImage(ctx context.Context, input *dtos.UploadFileRequest) (*dtos.SingleProductImageResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+
+	image, err := h.service.UploadProductImage(input.ID, file.Filename, file, file.Size, actorFromContext(ctx))
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	return &dtos.SingleProductImageResponse{Body: image}, nil
+}
+
+func (h *AttachmentHandler) ListProductImages(ctx context.Context, input *dtos.IDParam) (*dtos.ProductImageListResponse, error) {
+	images, err := h.service.GetProductImages(input.ID)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+
+	resp := &dtos.ProductImageListResponse{}
+	resp.Body.Images = images
+	return resp, nil
+}
+
+func (h *AttachmentHandler) DownloadProductImage(ctx context.Context, input *dtos.ProductImageParam) (*huma.StreamResponse, error) {
+	file, err := h.service.OpenProductImage(input.ID, input.ImageID, false)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return streamFile(file, "inline"), nil
+}
+
+func (h *AttachmentHandler) DownloadProductThumbnail(ctx context.Context, input *dtos.ProductImageParam) (*huma.StreamResponse, error) {
+	file, err := h.service.OpenProductImage(input.ID, input.ImageID, true)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return streamFile(file, "inline"), nil
+}
+
+func (h *AttachmentHandler) DeleteProductImage(ctx context.Context, input *dtos.ProductImageParam) (*dtos.EmptyResponse, error) {
+	// Only admins can delete product images
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can delete product images")
+	}
+
+	if err := h.service.DeleteProductImage(input.ID, input.ImageID, actorFromContext(ctx)); err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return &dtos.EmptyResponse{}, nil
+}
+
+func (h *AttachmentHandler) UploadTransactionAttachment(ctx context.Context, input *dtos.UploadFileRequest) (*dtos.SingleAttachmentResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+
+	attachment, err := h.service.UploadTransactionAttachment(input.ID, file.Filename, file, file.Size, actorFromContext(ctx))
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	return &dtos.SingleAttachmentResponse{Body: attachment}, nil
+}
+
+func (h *AttachmentHandler) ListTransactionAttachments(ctx context.Context, input *dtos.IDParam) (*dtos.AttachmentListResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	attachments, err := h.service.GetTransactionAttachments(input.ID)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+
+	resp := &dtos.AttachmentListResponse{}
+	resp.Body.Attachments = attachments
+	return resp, nil
+}
+
+func (h *AttachmentHandler) DownloadTransactionAttachment(ctx context.Context, input *dtos.AttachmentParam) (*huma.StreamResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	file, err := h.service.OpenTransactionAttachment(input.ID, input.AttachmentID)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return streamFile(file, "attachment"), nil
+}
+
+// uploadError maps upload failures to HTTP errors
+func uploadError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrFileTooLarge), errors.Is(err, services.ErrImageTooLarge):
+		return huma.NewError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		return huma.Error415UnsupportedMediaType(err.Error())
+	}
+	return huma.Error400BadRequest(err.Error())
+}
+
+// streamFile writes a stored file to the response without buffering it in memory
+func streamFile(file *services.FileDownload, disposition string) *huma.StreamResponse {
+	return &huma.StreamResponse{
+		Body: func(ctx huma.Context) {
+			defer file.Content.Close()
+
+			ctx.SetHeader("Content-Type", file.ContentType)
+			ctx.SetHeader("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, file.FileName))
+			ctx.SetHeader("X-Content-Type-Options", "nosniff")
+			if file.Size > 0 {
+				ctx.SetHeader("Content-Length", strconv.FormatInt(file.Size, 10))
+			}
+
+			if _, err := io.Copy(ctx.BodyWriter(), file.Content); err != nil {
+				log.Printf("Failed to stream file %s: %v", file.FileName, err)
+			}
+		},
+	}
+}
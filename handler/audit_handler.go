@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"log"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-audit-log",
+		Method:      http.MethodGet,
+		Path:        "/audit",
+		Summary:     "List audit log entries (admin only)",
+		Description: "Returns recorded changes newest first, filtered by actor, entity, action, request ID and time range.",
+		Tags:        []string{"Audit"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ListAuditLogs)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "export-audit-log",
+		Method:      http.MethodGet,
+		Path:        "/audit/export",
+		Summary:     "Export audit log entries (admin only)",
+		Description: "Streams all matching entries oldest first as CSV or newline-delimited JSON.",
+		Tags:        []string{"Audit"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ExportAuditLogs)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "verify-audit-log",
+		Method:      http.MethodGet,
+		Path:        "/audit/verify",
+		Summary:     "Verify the audit log hash chain (admin only)",
+		Tags:        []string{"Audit"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.VerifyAuditLog)
+}
+
+func (h *AuditHandler) ListAuditLogs(ctx context.Context, input *dtos.AuditListQuery) (*dtos.AuditLogListResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can view the audit log")
+	}
+
+	page := input.Page()
+	entries, info, err := h.service.GetAuditLogs(input.ToAuditFilter(), page)
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	resp := &dtos.AuditLogListResponse{}
+	resp.Body.Entries = entries
+	resp.Body.Limit = input.Limit
+	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
+	return resp, nil
+}
+
+func (h *AuditHandler) ExportAuditLogs(ctx context.Context, input *dtos.AuditExportQuery) (*huma.StreamResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can export the audit log")
+	}
+
+	contentType := "text/csv"
+	if input.Format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+	filter := input.ToAuditFilter()
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", contentType)
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "audit-log."+input.Format))
+
+			// Headers are already sent, so a failure can only be logged
+			if err := h.service.Export(filter, input.Format, hctx.BodyWriter()); err != nil {
+				log.Printf("Failed to export audit log: %v", err)
+			}
+		},
+	}, nil
+}
+
+func (h *AuditHandler) VerifyAuditLog(ctx context.Context, input *struct{}) (*dtos.AuditVerifyResult, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can verify the audit log")
+	}
+
+	result, err := h.service.Verify()
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.AuditVerifyResult{Body: result}, nil
+}
+
+// actorFromContext describes the authenticated caller and request for the audit log
+func actorFromContext(ctx context.Context) services.Actor {
+	var actor services.Actor
+	if auth := middleware.GetAuthContext(ctx); auth != nil {
+		actor.UserID = auth.UserID
+		actor.Username = auth.Username
+	}
+	if info := middleware.GetRequestInfo(ctx); info != nil {
+		actor.RequestID = info.ID
+		actor.IP = info.IP
+	}
+	return actor
+}
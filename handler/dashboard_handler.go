@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type DashboardHandler struct {
+	service *services.DashboardService
+}
+
+func NewDashboardHandler(service *services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{service: service}
+}
+
+func (h *DashboardHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-dashboard",
+		Method:      http.MethodGet,
+		Path:        "/dashboard",
+		Summary:     "Inventory KPI dashboard",
+		Description: "Returns stock value, stockout and low stock counts, turnover, days of supply, top movers and the daily IN/OUT trend. Figures are cached for DASHBOARD_CACHE_TTL. Non-admins get top movers and the trend of their own movements only, without turnover and days of supply.",
+		Tags:        []string{"Dashboard"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.GetDashboard)
+}
+
+func (h *DashboardHandler) GetDashboard(ctx context.Context, input *dtos.DashboardQuery) (*dtos.DashboardResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	options := &dtos.DashboardOptions{Days: input.Days, Top: input.Top}
+	// Non-admins only see their own movements, as in the user movement report
+	if !middleware.IsAdmin(ctx) {
+		options.UserID = &auth.UserID
+	}
+
+	dashboard, err := h.service.GetDashboard(options)
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.DashboardResponse{
+		CacheControl: fmt.Sprintf("private, max-age=%d", int(h.service.CacheTTL().Seconds())),
+		Body:         dashboard,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// errWeakETag is returned for weak If-Match validators. If-Match uses strong
+// comparison, so a weak ETag never matches.
+var errWeakETag = errors.New("If-Match requires a strong ETag")
+
+// formatETag builds a strong ETag from a record version
+func formatETag(version uint) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// parseIfMatch extracts the expected version from an If-Match header.
+// It returns 0 when the header is empty or "*", meaning any version matches.
+func parseIfMatch(header string) (uint, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errors.New("If-Match with multiple ETags is not supported")
+	}
+
+	if strings.HasPrefix(header, "W/") {
+		return 0, errWeakETag
+	}
+	tag := strings.Trim(header, "\"")
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.New("invalid If-Match header")
+	}
+	return uint(version), nil
+}
+
+// expectedVersion validates the If-Match header of an update request
+func expectedVersion(ifMatch string, required bool) (uint, error) {
+	if required && strings.TrimSpace(ifMatch) == "" {
+		return 0, huma.NewError(http.StatusPreconditionRequired, "If-Match header is required for updates")
+	}
+	version, err := parseIfMatch(ifMatch)
+	if errors.Is(err, errWeakETag) {
+		return 0, huma.Error412PreconditionFailed(err.Error())
+	}
+	if err != nil {
+		return 0, huma.Error400BadRequest(err.Error())
+	}
+	return version, nil
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	tests := []struct {
+		ifMatch  string
+		required bool
+		version  uint
+		status   int
+	}{
+		{`"3"`, false, 3, 0},
+		{` "3" `, true, 3, 0},
+		{"", false, 0, 0},
+		{"*", true, 0, 0},
+		{"", true, 0, http.StatusPreconditionRequired},
+		{`W/"3"`, false, 0, http.StatusPreconditionFailed},
+		{`"1", "2"`, false, 0, http.StatusBadRequest},
+		{`"abc"`, false, 0, http.StatusBadRequest},
+		{`"0"`, false, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		version, err := expectedVersion(tt.ifMatch, tt.required)
+		status := 0
+		var statusErr huma.StatusError
+		if errors.As(err, &statusErr) {
+			status = statusErr.GetStatus()
+		} else if err != nil {
+			t.Fatalf("If-Match %q: unexpected error %v", tt.ifMatch, err)
+		}
+		if version != tt.version || status != tt.status {
+			t.Errorf("If-Match %q: got version %d, status %d; want %d, %d", tt.ifMatch, version, status, tt.version, tt.status)
+		}
+	}
+}
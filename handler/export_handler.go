@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+func (h *ExportHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "export-products",
+		Method:      http.MethodGet,
+		Path:        "/products/export",
+		Summary:     "Export products",
+		Description: "Streams every product matching the filters as CSV, XLSX or newline-delimited JSON, read from one consistent snapshot.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ExportProducts)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "export-transactions",
+		Method:      http.MethodGet,
+		Path:        "/transactions/export",
+		Summary:     "Export transactions",
+		Description: "Streams every transaction matching the filters oldest first as CSV, XLSX or newline-delimited JSON, read from one consistent snapshot.",
+		Tags:        []string{"Transactions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ExportTransactions)
+}
+
+func (h *ExportHandler) ExportProducts(ctx context.Context, input *dtos.ProductExportQuery) (*huma.StreamResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+	// Only admins can see soft-deleted products
+	if input.IncludeDeleted && !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can include deleted products")
+	}
+
+	filter := input.ToProductFilter()
+	return exportStream("products", input.Format, func(w io.Writer) error {
+		return h.service.ExportProducts(filter, input.Format, w, nil)
+	}), nil
+}
+
+func (h *ExportHandler) ExportTransactions(ctx context.Context, input *dtos.TransactionExportQuery) (*huma.StreamResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	filter := input.ToTransactionFilter()
+	return exportStream("transactions", input.Format, func(w io.Writer) error {
+		return h.service.ExportTransactions(filter, input.Format, w, nil)
+	}), nil
+}
+
+// exportStream sends the output of export as a timestamped attachment
+func exportStream(name, format string, export func(io.Writer) error) *huma.StreamResponse {
+	fileName := services.ExportFileName(name, format)
+
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Content-Type", services.ExportContentType(format))
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+			// Headers are already sent, so a failure can only be logged
+			if err := export(hctx.BodyWriter()); err != nil {
+				log.Printf("Failed to export %s: %v", name, err)
+			}
+		},
+	}
+}
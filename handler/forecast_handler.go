@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ForecastHandler struct {
+	service *services.ForecastService
+}
+
+func NewForecastHandler(service *services.ForecastService) *ForecastHandler {
+	return &ForecastHandler{service: service}
+}
+
+func (h *ForecastHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-product-forecast",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/forecast",
+		Summary:     "Demand forecast of a product",
+		Description: "Forecast daily OUT quantity from today on with 95% prediction intervals. Moving averages and Holt-Winters with weekly seasonality are backtested on the product's OUT history and the model with the lowest error is used. Forecasts are recalculated nightly by a product_forecast job.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.GetProductForecast)
+}
+
+func (h *ForecastHandler) GetProductForecast(ctx context.Context, input *dtos.ProductForecastQuery) (*dtos.ProductForecastResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	forecast, err := h.service.GetProductForecast(input.ID, input.Horizon)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			return nil, huma.Error404NotFound(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.ProductForecastResponse{Body: forecast}, nil
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ImportHandler struct {
+	service *services.ImportService
+}
+
+func NewImportHandler(service *services.ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+func (h *ImportHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:  "import-products",
+		Method:       http.MethodPost,
+		Path:         "/products/import",
+		Summary:      "Import products from CSV or XLSX",
+		Description:  "Creates or updates products by SKU. Use dry_run=true to get the per-row validation report without saving.",
+		Tags:         []string{"Products"},
+		MaxBodyBytes: h.service.MaxFileSize() + multipartOverhead,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ImportProducts)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "enqueue-product-import",
+		Method:        http.MethodPost,
+		Path:          "/jobs/product-import",
+		Summary:       "Import products in a background job",
+		Description:   "Accepts the same form as POST /products/import and returns a job to poll at GET /jobs/{id}. The per-row report is the job's downloadable result.",
+		Tags:          []string{"Jobs"},
+		DefaultStatus: http.StatusAccepted,
+		MaxBodyBytes:  h.service.MaxFileSize() + multipartOverhead,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.EnqueueImport)
+}
+
+func (h *ImportHandler) ImportProducts(ctx context.Context, input *dtos.ImportProductsRequest) (*dtos.ImportProductsResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	options, err := input.Options()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+
+	report, err := h.service.ImportProducts(file, file.Filename, file.Size, options, actorFromContext(ctx), nil)
+	if err != nil {
+		return nil, importError(err)
+	}
+	return &dtos.ImportProductsResponse{Body: report}, nil
+}
+
+func (h *ImportHandler) EnqueueImport(ctx context.Context, input *dtos.ImportProductsRequest) (*dtos.SingleJobResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	options, err := input.Options()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	file := input.RawBody.Data().File
+	defer file.Close()
+
+	job, err := h.service.EnqueueImport(file, file.Filename, file.Size, options, actorFromContext(ctx))
+	if err != nil {
+		return nil, importError(err)
+	}
+	return &dtos.SingleJobResponse{Location: jobLocation(job.ID), Body: job}, nil
+}
+
+// importError maps import failures to HTTP errors
+func importError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrFileTooLarge):
+		return huma.NewError(http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrInvalidImportFile):
+		return huma.Error400BadRequest(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
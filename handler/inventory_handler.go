@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"inventory-api/dtos"
 	"inventory-api/middleware"
 	"inventory-api/services"
@@ -11,11 +13,17 @@ import (
 )
 
 type InventoryHandler struct {
-	service *services.InventoryService
+	service        *services.InventoryService
+	idempotency    *services.IdempotencyService
+	requireIfMatch bool
 }
 
-func NewInventoryHandler(service *services.InventoryService) *InventoryHandler {
-	return &InventoryHandler{service: service}
+func NewInventoryHandler(service *services.InventoryService, idempotency *services.IdempotencyService, requireIfMatch bool) *InventoryHandler {
+	return &InventoryHandler{
+		service:        service,
+		idempotency:    idempotency,
+		requireIfMatch: requireIfMatch,
+	}
 }
 
 func (h *InventoryHandler) RegisterRoutes(api huma.API) {
@@ -58,6 +66,17 @@ func (h *InventoryHandler) RegisterRoutes(api huma.API) {
 		},
 	}, h.UpdateProduct)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "change-product-status",
+		Method:      http.MethodPost,
+		Path:        "/products/{id}/status",
+		Summary:     "Change product lifecycle status",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ChangeProductStatus)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "delete-product",
 		Method:      http.MethodDelete,
@@ -69,6 +88,119 @@ func (h *InventoryHandler) RegisterRoutes(api huma.API) {
 		},
 	}, h.DeleteProduct)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "bulk-update-products",
+		Method:      http.MethodPost,
+		Path:        "/products/bulk",
+		Summary:     "Change many products at once",
+		Description: "Sets or adjusts prices, sets the status or category, or deletes every product given by ids or matching a filter. Products the operation cannot apply to are reported as invalid and skipped; the rest are saved in one transaction and audited as a single entry. Use dry_run=true to preview the affected products.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.BulkUpdateProducts)
+
+	// Revision routes
+	huma.Register(api, huma.Operation{
+		OperationID: "list-product-revisions",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/revisions",
+		Summary:     "List product revisions",
+		Description: "Returns the snapshots stored on each product update, newest first.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ListProductRevisions)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "diff-product-revision",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/revisions/{n}/diff",
+		Summary:     "Compare a product revision with the previous one",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.GetProductRevisionDiff)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "rollback-product",
+		Method:      http.MethodPost,
+		Path:        "/products/{id}/revisions/{n}/rollback",
+		Summary:     "Roll a product back to a revision (admin only)",
+		Description: "Restores the name, description and price of the revision as a regular update. Quantity is not rolled back.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.RollbackProduct)
+
+	// Trash routes - admin only
+	huma.Register(api, huma.Operation{
+		OperationID: "list-deleted-products",
+		Method:      http.MethodGet,
+		Path:        "/products/trash",
+		Summary:     "List soft-deleted products (admin only)",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ListDeletedProducts)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-product",
+		Method:      http.MethodPost,
+		Path:        "/products/trash/{id}/restore",
+		Summary:     "Restore a soft-deleted product (admin only)",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.RestoreProduct)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "purge-product",
+		Method:      http.MethodDelete,
+		Path:        "/products/trash/{id}",
+		Summary:     "Permanently delete a soft-deleted product without transactions (admin only)",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.PurgeProduct)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "purge-deleted-products",
+		Method:      http.MethodDelete,
+		Path:        "/products/trash",
+		Summary:     "Permanently delete all soft-deleted products without transactions (admin only)",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.PurgeDeletedProducts)
+
+	// Category routes - listing is public, creating is admin only
+	huma.Register(api, huma.Operation{
+		OperationID: "list-categories",
+		Method:      http.MethodGet,
+		Path:        "/categories",
+		Summary:     "List product categories",
+		Tags:        []string{"Categories"},
+	}, h.ListCategories)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "create-category",
+		Method:      http.MethodPost,
+		Path:        "/categories",
+		Summary:     "Create a product category (admin only)",
+		Tags:        []string{"Categories"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.CreateCategory)
+
 	// Transaction routes - require authentication
 	huma.Register(api, huma.Operation{
 		OperationID: "create-transaction",
@@ -81,6 +213,17 @@ func (h *InventoryHandler) RegisterRoutes(api huma.API) {
 		},
 	}, h.CreateTransaction)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "create-transaction-batch",
+		Method:      http.MethodPost,
+		Path:        "/transactions/batch",
+		Summary:     "Create many transactions atomically under one document reference",
+		Tags:        []string{"Transactions"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.CreateTransactionBatch)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "get-transaction",
 		Method:      http.MethodGet,
@@ -122,34 +265,62 @@ func (h *InventoryHandler) CreateProduct(ctx context.Context, input *dtos.Create
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	product, err := h.service.CreateProduct(&input.Body)
+	product, err := services.Idempotent(h.idempotency, auth.UserID, "create-product", input.IdempotencyKey, input.Body, func() (*dtos.ProductResponse, error) {
+		return h.service.CreateProduct(&input.Body, actorFromContext(ctx))
+	})
 	if err != nil {
+		if idemErr := idempotencyError(err); idemErr != nil {
+			return nil, idemErr
+		}
 		return nil, huma.Error400BadRequest(err.Error())
 	}
-	return &dtos.SingleProductResponse{Body: product}, nil
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
 }
 
-func (h *InventoryHandler) GetProduct(ctx context.Context, input *dtos.IDParam) (*dtos.SingleProductResponse, error) {
-	product, err := h.service.GetProductByID(input.ID)
+func (h *InventoryHandler) GetProduct(ctx context.Context, input *dtos.ProductDetailQuery) (*dtos.SingleProductResponse, error) {
+	projection, err := input.Projection()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	product, err := h.service.GetProductByID(input.ID, projection)
 	if err != nil {
 		return nil, huma.Error404NotFound(err.Error())
 	}
-	return &dtos.SingleProductResponse{Body: product}, nil
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
 }
 
 func (h *InventoryHandler) ListProducts(ctx context.Context, input *dtos.ProductListQuery) (*dtos.ProductListResponse, error) {
+	// Only admins can see soft-deleted products
+	if input.IncludeDeleted && !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can include deleted products")
+	}
+
 	// Convert query to filter
 	filter := input.ToProductFilter()
+	sort, err := dtos.ParseSort(input.Sort, dtos.ProductSortFields)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	projection, err := input.Projection()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
 
-	products, err := h.service.GetProductsWithFilter(filter, input.Limit, input.Offset)
+	page := input.Page()
+	products, info, err := h.service.GetProductsWithFilter(filter, sort, page, projection)
 	if err != nil {
-		return nil, huma.Error500InternalServerError(err.Error())
+		return nil, listError(err)
 	}
 
 	resp := &dtos.ProductListResponse{}
 	resp.Body.Products = products
 	resp.Body.Limit = input.Limit
 	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
 	return resp, nil
 }
 
@@ -160,11 +331,102 @@ func (h *InventoryHandler) UpdateProduct(ctx context.Context, input *dtos.Update
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	product, err := h.service.UpdateProduct(input.ID, &input.Body)
+	version, err := expectedVersion(input.IfMatch, h.requireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := h.service.UpdateProduct(input.ID, &input.Body, version, actorFromContext(ctx))
 	if err != nil {
+		if errors.Is(err, services.ErrVersionMismatch) {
+			return nil, huma.Error412PreconditionFailed(err.Error())
+		}
 		return nil, huma.Error400BadRequest(err.Error())
 	}
-	return &dtos.SingleProductResponse{Body: product}, nil
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
+}
+
+func (h *InventoryHandler) ListProductRevisions(ctx context.Context, input *dtos.ProductRevisionsQuery) (*dtos.ProductRevisionListResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	page := input.Page()
+	revisions, info, err := h.service.GetProductRevisions(input.ID, page)
+	if err != nil {
+		if isPageError(err) {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return nil, huma.Error404NotFound(err.Error())
+	}
+
+	resp := &dtos.ProductRevisionListResponse{}
+	resp.Body.Revisions = revisions
+	resp.Body.Limit = input.Limit
+	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
+	return resp, nil
+}
+
+func (h *InventoryHandler) GetProductRevisionDiff(ctx context.Context, input *dtos.ProductRevisionParam) (*dtos.ProductRevisionDiffResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	diff, err := h.service.GetProductRevisionDiff(input.ID, input.Revision)
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return &dtos.ProductRevisionDiffResponse{Body: diff}, nil
+}
+
+func (h *InventoryHandler) RollbackProduct(ctx context.Context, input *dtos.RollbackProductRequest) (*dtos.SingleProductResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can roll back products")
+	}
+
+	version, err := expectedVersion(input.IfMatch, h.requireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := h.service.RollbackProduct(input.ID, input.Revision, version, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrVersionMismatch) {
+			return nil, huma.Error412PreconditionFailed(err.Error())
+		}
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
+}
+
+func (h *InventoryHandler) ChangeProductStatus(ctx context.Context, input *dtos.ChangeProductStatusRequest) (*dtos.SingleProductResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	version, err := expectedVersion(input.IfMatch, h.requireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := h.service.ChangeProductStatus(input.ID, input.Body.Status, version, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrVersionMismatch) {
+			return nil, huma.Error412PreconditionFailed(err.Error())
+		}
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
 }
 
 func (h *InventoryHandler) DeleteProduct(ctx context.Context, input *dtos.IDParam) (*dtos.EmptyResponse, error) {
@@ -173,13 +435,130 @@ func (h *InventoryHandler) DeleteProduct(ctx context.Context, input *dtos.IDPara
 		return nil, huma.Error403Forbidden("Only admins can delete products")
 	}
 
-	err := h.service.DeleteProduct(input.ID)
+	err := h.service.DeleteProduct(input.ID, actorFromContext(ctx))
+	if err != nil {
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return &dtos.EmptyResponse{}, nil
+}
+
+func (h *InventoryHandler) BulkUpdateProducts(ctx context.Context, input *dtos.BulkProductRequest) (*dtos.BulkProductResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+	// Only admins can delete products
+	if input.Body.Operation == dtos.BulkOperationDelete && !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can delete products")
+	}
+
+	report, err := h.service.BulkUpdateProducts(&input.Body, input.DryRun, actorFromContext(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBulkTarget),
+			errors.Is(err, services.ErrInvalidBulkOperation),
+			errors.Is(err, services.ErrBulkTooManyProducts),
+			errors.Is(err, services.ErrCategoryNotFound):
+			return nil, huma.Error400BadRequest(err.Error())
+		case errors.Is(err, services.ErrVersionMismatch):
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.BulkProductResponse{Body: report}, nil
+}
+
+func (h *InventoryHandler) ListDeletedProducts(ctx context.Context, input *dtos.PaginationQuery) (*dtos.ProductListResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can view deleted products")
+	}
+
+	page := input.Page()
+	products, info, err := h.service.GetDeletedProducts(page)
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	resp := &dtos.ProductListResponse{}
+	resp.Body.Products = products
+	resp.Body.Limit = input.Limit
+	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
+	return resp, nil
+}
+
+func (h *InventoryHandler) RestoreProduct(ctx context.Context, input *dtos.IDParam) (*dtos.SingleProductResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can restore products")
+	}
+
+	product, err := h.service.RestoreProduct(input.ID, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrSKUConflict) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		return nil, huma.Error404NotFound(err.Error())
+	}
+	return &dtos.SingleProductResponse{ETag: formatETag(product.Version), Body: product}, nil
+}
+
+func (h *InventoryHandler) PurgeProduct(ctx context.Context, input *dtos.IDParam) (*dtos.EmptyResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can purge products")
+	}
+
+	err := h.service.PurgeProduct(input.ID, actorFromContext(ctx))
 	if err != nil {
+		if errors.Is(err, services.ErrProductHasTransactions) {
+			return nil, huma.Error409Conflict(err.Error())
+		}
 		return nil, huma.Error404NotFound(err.Error())
 	}
 	return &dtos.EmptyResponse{}, nil
 }
 
+func (h *InventoryHandler) PurgeDeletedProducts(ctx context.Context, input *struct{}) (*dtos.PurgeProductsResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can purge products")
+	}
+
+	purged, err := h.service.PurgeDeletedProducts(actorFromContext(ctx))
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+
+	resp := &dtos.PurgeProductsResponse{}
+	resp.Body.Purged = purged
+	return resp, nil
+}
+
+func (h *InventoryHandler) ListCategories(ctx context.Context, input *struct{}) (*dtos.CategoryListResponse, error) {
+	categories, err := h.service.GetCategories()
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+
+	resp := &dtos.CategoryListResponse{}
+	resp.Body.Categories = categories
+	return resp, nil
+}
+
+func (h *InventoryHandler) CreateCategory(ctx context.Context, input *dtos.CreateCategoryRequest) (*dtos.SingleCategoryResponse, error) {
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can create categories")
+	}
+
+	category, err := h.service.CreateCategory(&input.Body, actorFromContext(ctx))
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	return &dtos.SingleCategoryResponse{Body: category}, nil
+}
+
 func (h *InventoryHandler) CreateTransaction(ctx context.Context, input *dtos.CreateTransactionRequest) (*dtos.SingleTransactionResponse, error) {
 	// Verify authentication
 	auth := middleware.GetAuthContext(ctx)
@@ -187,42 +566,91 @@ func (h *InventoryHandler) CreateTransaction(ctx context.Context, input *dtos.Cr
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	transaction, err := h.service.CreateTransaction(&input.Body)
+	transaction, err := services.Idempotent(h.idempotency, auth.UserID, "create-transaction", input.IdempotencyKey, input.Body, func() (*dtos.TransactionResponse, error) {
+		return h.service.CreateTransaction(&input.Body, actorFromContext(ctx))
+	})
 	if err != nil {
+		if idemErr := idempotencyError(err); idemErr != nil {
+			return nil, idemErr
+		}
 		return nil, huma.Error400BadRequest(err.Error())
 	}
 	return &dtos.SingleTransactionResponse{Body: transaction}, nil
 }
 
-func (h *InventoryHandler) GetTransaction(ctx context.Context, input *dtos.IDParam) (*dtos.SingleTransactionResponse, error) {
+func (h *InventoryHandler) CreateTransactionBatch(ctx context.Context, input *dtos.CreateTransactionBatchRequest) (*dtos.TransactionBatchResult, error) {
 	// Verify authentication
 	auth := middleware.GetAuthContext(ctx)
 	if auth == nil {
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	transaction, err := h.service.GetTransactionByID(input.ID)
+	batch, err := services.Idempotent(h.idempotency, auth.UserID, "create-transaction-batch", input.IdempotencyKey, input.Body, func() (*dtos.TransactionBatchResponse, error) {
+		return h.service.CreateTransactionBatch(&input.Body, actorFromContext(ctx))
+	})
+	if err != nil {
+		if idemErr := idempotencyError(err); idemErr != nil {
+			return nil, idemErr
+		}
+		var batchErr *dtos.BatchValidationError
+		if errors.As(err, &batchErr) {
+			return nil, batchValidationError(batchErr)
+		}
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	return &dtos.TransactionBatchResult{Body: batch}, nil
+}
+
+func (h *InventoryHandler) GetTransaction(ctx context.Context, input *dtos.TransactionDetailQuery) (*dtos.SingleTransactionResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	projection, err := input.Projection()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	transaction, err := h.service.GetTransactionByID(input.ID, projection)
 	if err != nil {
 		return nil, huma.Error404NotFound(err.Error())
 	}
 	return &dtos.SingleTransactionResponse{Body: transaction}, nil
 }
 
-func (h *InventoryHandler) ListTransactions(ctx context.Context, input *dtos.PaginationQuery) (*dtos.TransactionListResponse, error) {
+func (h *InventoryHandler) ListTransactions(ctx context.Context, input *dtos.TransactionListQuery) (*dtos.TransactionListResponse, error) {
 	// Verify authentication
 	auth := middleware.GetAuthContext(ctx)
 	if auth == nil {
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	transactions, err := h.service.GetAllTransactions(input.Limit, input.Offset)
+	// Convert query to filter
+	filter := input.ToTransactionFilter()
+	sort, err := dtos.ParseSort(input.Sort, dtos.TransactionSortFields)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	projection, err := input.Projection()
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	page := input.Page()
+	transactions, info, err := h.service.GetTransactionsWithFilter(filter, sort, page, projection)
 	if err != nil {
-		return nil, huma.Error500InternalServerError(err.Error())
+		return nil, listError(err)
 	}
 	resp := &dtos.TransactionListResponse{}
 	resp.Body.Transactions = transactions
 	resp.Body.Limit = input.Limit
 	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
 	return resp, nil
 }
 
@@ -233,13 +661,65 @@ func (h *InventoryHandler) ListProductTransactions(ctx context.Context, input *d
 		return nil, huma.Error401Unauthorized("Authentication required")
 	}
 
-	transactions, err := h.service.GetTransactionsByProductID(input.ID, input.Limit, input.Offset)
+	projection, err := input.Projection()
 	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	page := input.Page()
+	transactions, info, err := h.service.GetTransactionsByProductID(input.ID, page, projection)
+	if err != nil {
+		if isPageError(err) {
+			return nil, listError(err)
+		}
 		return nil, huma.Error404NotFound(err.Error())
 	}
 	resp := &dtos.TransactionListResponse{}
 	resp.Body.Transactions = transactions
 	resp.Body.Limit = input.Limit
 	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
 	return resp, nil
 }
+
+// isPageError reports whether err was caused by invalid pagination parameters
+func isPageError(err error) bool {
+	return errors.Is(err, services.ErrInvalidCursor) ||
+		errors.Is(err, services.ErrCursorWithOffset) ||
+		errors.Is(err, services.ErrCursorNotSupported)
+}
+
+// listError maps pagination errors to 400 and everything else to 500
+func listError(err error) error {
+	if isPageError(err) {
+		return huma.Error400BadRequest(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
+
+// idempotencyError maps Idempotency-Key conflicts to HTTP errors, or returns nil
+func idempotencyError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		return huma.Error422UnprocessableEntity(err.Error())
+	case errors.Is(err, services.ErrIdempotencyInProgress), errors.Is(err, services.ErrIdempotencyLeaseLost):
+		return huma.Error409Conflict(err.Error())
+	}
+	return nil
+}
+
+// batchValidationError converts per-line batch errors into a 422 with one detail per line
+func batchValidationError(batchErr *dtos.BatchValidationError) error {
+	details := make([]error, len(batchErr.Errors))
+	for i, lineErr := range batchErr.Errors {
+		details[i] = &huma.ErrorDetail{
+			Message:  lineErr.Message,
+			Location: fmt.Sprintf("body.lines[%d].%s", lineErr.Line, lineErr.Field),
+			Value:    lineErr.Value,
+		}
+	}
+	return huma.Error422UnprocessableEntity(batchErr.Error(), details...)
+}
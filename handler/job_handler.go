@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"net/http"
+	"slices"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type JobHandler struct {
+	service *services.JobService
+}
+
+func NewJobHandler(service *services.JobService) *JobHandler {
+	return &JobHandler{service: service}
+}
+
+func (h *JobHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "create-job",
+		Method:        http.MethodPost,
+		Path:          "/jobs",
+		Summary:       "Start a background job",
+		Description:   "Queues an export or report to run in the background. Poll GET /jobs/{id} for progress and download the result from GET /jobs/{id}/result.",
+		Tags:          []string{"Jobs"},
+		DefaultStatus: http.StatusAccepted,
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.CreateJob)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-jobs",
+		Method:      http.MethodGet,
+		Path:        "/jobs",
+		Summary:     "List background jobs",
+		Description: "Returns your jobs newest first; admins see everyone's.",
+		Tags:        []string{"Jobs"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ListJobs)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-job",
+		Method:      http.MethodGet,
+		Path:        "/jobs/{id}",
+		Summary:     "Get a background job",
+		Tags:        []string{"Jobs"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.GetJob)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "cancel-job",
+		Method:      http.MethodPost,
+		Path:        "/jobs/{id}/cancel",
+		Summary:     "Cancel a background job",
+		Description: "Queued jobs are cancelled right away; running jobs stop at their next progress report.",
+		Tags:        []string{"Jobs"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.CancelJob)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "download-job-result",
+		Method:      http.MethodGet,
+		Path:        "/jobs/{id}/result",
+		Summary:     "Download the result of a background job",
+		Tags:        []string{"Jobs"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.DownloadJobResult)
+}
+
+func (h *JobHandler) CreateJob(ctx context.Context, input *dtos.CreateJobRequest) (*dtos.SingleJobResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+	isAdmin := middleware.IsAdmin(ctx)
+
+	// Decode and authorize the parameters the same way the synchronous endpoints do
+	var params interface{}
+	switch input.Body.Type {
+	case dtos.JobTypeProductExport:
+		var p dtos.ProductExportJobParams
+		if err := input.Body.DecodeParams(&p); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		if p.Format == "" {
+			p.Format = services.ExportFormatCSV
+		}
+		if !slices.Contains(dtos.ExportFormats, p.Format) {
+			return nil, huma.Error400BadRequest("format must be csv, xlsx or ndjson")
+		}
+		if p.Filter.IncludeDeleted && !isAdmin {
+			return nil, huma.Error403Forbidden("Only admins can include deleted products")
+		}
+		params = p
+	case dtos.JobTypeTransactionExport:
+		var p dtos.TransactionExportJobParams
+		if err := input.Body.DecodeParams(&p); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		if p.Format == "" {
+			p.Format = services.ExportFormatCSV
+		}
+		if !slices.Contains(dtos.ExportFormats, p.Format) {
+			return nil, huma.Error400BadRequest("format must be csv, xlsx or ndjson")
+		}
+		params = p
+	case dtos.JobTypeUserMovementReport:
+		var p dtos.UserMovementReportJobParams
+		if err := input.Body.DecodeParams(&p); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		// Non-admins can only report on themselves
+		if !isAdmin {
+			if p.Filter.UserID != nil && *p.Filter.UserID != auth.UserID {
+				return nil, huma.Error403Forbidden("Only admins can view other users' movements")
+			}
+			p.Filter.UserID = &auth.UserID
+		}
+		params = p
+	case dtos.JobTypeProductForecast:
+		if !isAdmin {
+			return nil, huma.Error403Forbidden("Only admins can recalculate forecasts")
+		}
+		var p dtos.ProductForecastJobParams
+		if err := input.Body.DecodeParams(&p); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		params = p
+	default:
+		return nil, huma.Error400BadRequest(services.ErrUnknownJobType.Error())
+	}
+
+	job, err := h.service.Enqueue(input.Body.Type, params, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownJobType) {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.SingleJobResponse{Location: jobLocation(job.ID), Body: job}, nil
+}
+
+func (h *JobHandler) ListJobs(ctx context.Context, input *dtos.JobListQuery) (*dtos.JobListResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	// Non-admins only see their own jobs
+	filter := input.ToJobFilter()
+	if !middleware.IsAdmin(ctx) {
+		if input.UserID != 0 && input.UserID != auth.UserID {
+			return nil, huma.Error403Forbidden("Only admins can view other users' jobs")
+		}
+		filter.CreatedBy = &auth.UserID
+	}
+
+	page := input.Page()
+	jobs, info, err := h.service.GetJobs(filter, page)
+	if err != nil {
+		return nil, listError(err)
+	}
+
+	resp := &dtos.JobListResponse{}
+	resp.Body.Jobs = jobs
+	resp.Body.Limit = input.Limit
+	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
+	return resp, nil
+}
+
+func (h *JobHandler) GetJob(ctx context.Context, input *dtos.IDParam) (*dtos.SingleJobResponse, error) {
+	job, err := h.authorizedJob(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &dtos.SingleJobResponse{Body: job}, nil
+}
+
+func (h *JobHandler) CancelJob(ctx context.Context, input *dtos.IDParam) (*dtos.SingleJobResponse, error) {
+	if _, err := h.authorizedJob(ctx, input.ID); err != nil {
+		return nil, err
+	}
+
+	job, err := h.service.CancelJob(input.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			return nil, huma.Error404NotFound(err.Error())
+		case errors.Is(err, services.ErrJobFinished):
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return &dtos.SingleJobResponse{Body: job}, nil
+}
+
+func (h *JobHandler) DownloadJobResult(ctx context.Context, input *dtos.IDParam) (*huma.StreamResponse, error) {
+	if _, err := h.authorizedJob(ctx, input.ID); err != nil {
+		return nil, err
+	}
+
+	file, err := h.service.OpenJobResult(input.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			return nil, huma.Error404NotFound(err.Error())
+		case errors.Is(err, services.ErrJobResultNotReady):
+			return nil, huma.Error409Conflict(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	return streamFile(file, "attachment"), nil
+}
+
+// authorizedJob loads a job the caller may see: their own, or any job for admins.
+// Other users' jobs are reported as missing so their IDs are not revealed.
+func (h *JobHandler) authorizedJob(ctx context.Context, id uint) (*dtos.JobResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	job, err := h.service.GetJob(id)
+	if err != nil {
+		if errors.Is(err, services.ErrJobNotFound) {
+			return nil, huma.Error404NotFound(err.Error())
+		}
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+	if !middleware.IsAdmin(ctx) && (job.CreatedBy == nil || *job.CreatedBy != auth.UserID) {
+		return nil, huma.Error404NotFound(services.ErrJobNotFound.Error())
+	}
+	return job, nil
+}
+
+// jobLocation is the URL to poll a job at
+func jobLocation(id uint) string {
+	return fmt.Sprintf("/jobs/%d", id)
+}
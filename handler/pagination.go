@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"strconv"
+	"strings"
+)
+
+// pageLinks builds an RFC 8288 Link header pointing at the neighbouring pages of
+// the current request. Offset pages link first/prev/next/last (last only when the
+// total is known); cursor pages only move forward, so they link first and next.
+func pageLinks(ctx context.Context, page dtos.PageRequest, info dtos.PageInfo) string {
+	req := middleware.GetRequestInfo(ctx)
+	if req == nil {
+		return ""
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	link := func(rel string, set map[string]string) string {
+		query := req.URL.Query()
+		query.Del("cursor")
+		query.Del("offset")
+		for key, value := range set {
+			query.Set(key, value)
+		}
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, req.URL.Path, query.Encode(), rel)
+	}
+
+	links := []string{link("first", nil)}
+	if page.Cursor != "" {
+		if info.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": info.NextCursor}))
+		}
+		return strings.Join(links, ", ")
+	}
+
+	if page.Offset > 0 {
+		prev := page.Offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", map[string]string{"offset": strconv.Itoa(prev)}))
+	}
+	if info.HasMore {
+		links = append(links, link("next", map[string]string{"offset": strconv.Itoa(page.Offset + limit)}))
+	}
+	if info.Total != nil && *info.Total > 0 {
+		last := (int(*info.Total) - 1) / limit * limit
+		links = append(links, link("last", map[string]string{"offset": strconv.Itoa(last)}))
+	}
+	return strings.Join(links, ", ")
+}
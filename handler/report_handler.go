@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/middleware"
+	"inventory-api/services"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type ReportHandler struct {
+	service *services.ReportService
+}
+
+func NewReportHandler(service *services.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+func (h *ReportHandler) RegisterRoutes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "report-user-movements",
+		Method:      http.MethodGet,
+		Path:        "/reports/user-movements",
+		Summary:     "Stock movements per user",
+		Description: "Totals IN and OUT movements by the user who recorded them. Non-admins only see their own movements.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.UserMovements)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "report-movements",
+		Method:      http.MethodGet,
+		Path:        "/reports/movements",
+		Summary:     "Stock movement summary per product and period",
+		Description: "Opening balance, IN, OUT, adjustments and closing balance of each product per day, week or month in [from, to). Balances are rebuilt from the transaction ledger.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.Movements)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-product-timeseries",
+		Method:      http.MethodGet,
+		Path:        "/products/{id}/timeseries",
+		Summary:     "Stock level time series of a product",
+		Description: "Opening and closing stock level, IN, OUT and adjustments of the product per day, week or month in [from, to), rebuilt from the transaction ledger. Every bucket of the range is returned, including those without movements.",
+		Tags:        []string{"Products"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ProductTimeseries)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "export-report-movements",
+		Method:      http.MethodGet,
+		Path:        "/reports/movements/export",
+		Summary:     "Export the stock movement summary",
+		Description: "Downloads the same rows as GET /reports/movements as CSV or XLSX.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ExportMovements)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "report-stock-aging",
+		Method:      http.MethodGet,
+		Path:        "/reports/aging",
+		Summary:     "Dead stock and inventory aging",
+		Description: "Lists products with stock on hand and no OUT movement in the given days, the value tied up in them and their on-hand quantity by age, assuming stock leaves first in, first out.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.StockAging)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "export-report-stock-aging",
+		Method:      http.MethodGet,
+		Path:        "/reports/aging/export",
+		Summary:     "Export the dead stock and aging report",
+		Description: "Downloads the same products as GET /reports/aging as CSV or XLSX, one quantity column per age bucket.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ExportStockAging)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "report-abc",
+		Method:      http.MethodGet,
+		Path:        "/reports/abc",
+		Summary:     "ABC analysis of products",
+		Description: "Ranks products by consumption value (OUT quantity in the window times the current price) and assigns A/B/C classes by cumulative share, without storing them.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ABCAnalysis)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "apply-abc",
+		Method:      http.MethodPost,
+		Path:        "/reports/abc/apply",
+		Summary:     "Store ABC classes on products",
+		Description: "Runs the ABC analysis and stores each product's class, which GET /products?abc_class= filters by. Admin only.",
+		Tags:        []string{"Reports"},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}, h.ApplyABC)
+}
+
+func (h *ReportHandler) UserMovements(ctx context.Context, input *dtos.UserMovementReportQuery) (*dtos.UserMovementReportResponse, error) {
+	// Verify authentication
+	auth := middleware.GetAuthContext(ctx)
+	if auth == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	// Non-admins can only report on themselves
+	filter := input.ToTransactionFilter()
+	if !middleware.IsAdmin(ctx) {
+		if input.UserID != 0 && input.UserID != auth.UserID {
+			return nil, huma.Error403Forbidden("Only admins can view other users' movements")
+		}
+		filter.UserID = &auth.UserID
+	}
+
+	users, err := h.service.GetUserMovements(filter)
+	if err != nil {
+		return nil, huma.Error500InternalServerError(err.Error())
+	}
+
+	resp := &dtos.UserMovementReportResponse{}
+	resp.Body.Users = users
+	return resp, nil
+}
+
+func (h *ReportHandler) Movements(ctx context.Context, input *dtos.MovementReportQuery) (*dtos.MovementReportResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	filter := input.ToMovementFilter()
+	rows, err := h.service.GetMovementSummary(filter)
+	if err != nil {
+		return nil, reportError(err)
+	}
+
+	resp := &dtos.MovementReportResponse{}
+	resp.Body.From = filter.From.UTC().Format(time.RFC3339)
+	resp.Body.To = filter.To.UTC().Format(time.RFC3339)
+	resp.Body.GroupBy = filter.GroupBy
+	resp.Body.Rows = rows
+	return resp, nil
+}
+
+func (h *ReportHandler) ProductTimeseries(ctx context.Context, input *dtos.ProductTimeseriesQuery) (*dtos.StockTimeseriesResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	series, err := h.service.GetProductTimeseries(input.ID, input.From, input.To, input.Interval)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			return nil, huma.Error404NotFound(err.Error())
+		}
+		return nil, reportError(err)
+	}
+	return &dtos.StockTimeseriesResponse{Body: series}, nil
+}
+
+func (h *ReportHandler) ExportMovements(ctx context.Context, input *dtos.MovementReportExportQuery) (*huma.StreamResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	// Build the report up front so an invalid range is still a proper error response
+	filter := input.ToMovementFilter()
+	rows, err := h.service.GetMovementSummary(filter)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return exportStream("stock-movements", input.Format, func(w io.Writer) error {
+		return h.service.WriteMovementSummary(rows, input.Format, w)
+	}), nil
+}
+
+func (h *ReportHandler) StockAging(ctx context.Context, input *dtos.AgingReportQuery) (*dtos.AgingReportResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	report, err := h.service.GetStockAging(input.Options())
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return &dtos.AgingReportResponse{Body: report}, nil
+}
+
+func (h *ReportHandler) ExportStockAging(ctx context.Context, input *dtos.AgingReportExportQuery) (*huma.StreamResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	report, err := h.service.GetStockAging(input.Options())
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return exportStream("stock-aging", input.Format, func(w io.Writer) error {
+		return h.service.WriteStockAging(report, input.Format, w)
+	}), nil
+}
+
+func (h *ReportHandler) ABCAnalysis(ctx context.Context, input *dtos.ABCReportQuery) (*dtos.ABCReportResponse, error) {
+	// Verify authentication
+	if middleware.GetAuthContext(ctx) == nil {
+		return nil, huma.Error401Unauthorized("Authentication required")
+	}
+
+	report, err := h.service.ABCAnalysis(input.Options(), false)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return &dtos.ABCReportResponse{Body: report}, nil
+}
+
+func (h *ReportHandler) ApplyABC(ctx context.Context, input *dtos.ABCReportQuery) (*dtos.ABCReportResponse, error) {
+	// Only admins can reclassify products
+	if !middleware.IsAdmin(ctx) {
+		return nil, huma.Error403Forbidden("Only admins can store ABC classes")
+	}
+
+	report, err := h.service.ABCAnalysis(input.Options(), true)
+	if err != nil {
+		return nil, reportError(err)
+	}
+	return &dtos.ABCReportResponse{Body: report}, nil
+}
+
+// reportError maps report service errors to HTTP errors
+func reportError(err error) error {
+	if errors.Is(err, services.ErrInvalidReportRange) || errors.Is(err, services.ErrInvalidABCOptions) {
+		return huma.Error400BadRequest(err.Error())
+	}
+	return huma.Error500InternalServerError(err.Error())
+}
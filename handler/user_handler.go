@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"inventory-api/dtos"
 	"inventory-api/middleware"
 	"inventory-api/services"
@@ -11,12 +12,14 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService    *services.UserService
+	requireIfMatch bool
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
+func NewUserHandler(userService *services.UserService, requireIfMatch bool) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:    userService,
+		requireIfMatch: requireIfMatch,
 	}
 }
 
@@ -113,12 +116,13 @@ func (h *UserHandler) Register(ctx context.Context, input *dtos.RegisterRequest)
 		input.Body.Email,
 		input.Body.Phone,
 		input.Body.Role,
+		actorFromContext(ctx),
 	)
 	if err != nil {
 		return nil, huma.Error400BadRequest(err.Error())
 	}
 
-	return &dtos.SingleUserResponse{Body: dtos.ToUserResponse(user)}, nil
+	return &dtos.SingleUserResponse{ETag: formatETag(user.Version), Body: dtos.ToUserResponse(user)}, nil
 }
 
 func (h *UserHandler) Login(ctx context.Context, input *dtos.LoginRequest) (*dtos.LoginResponse, error) {
@@ -144,7 +148,7 @@ func (h *UserHandler) GetProfile(ctx context.Context, input *struct{}) (*dtos.Si
 		return nil, huma.Error404NotFound("User not found")
 	}
 
-	return &dtos.SingleUserResponse{Body: dtos.ToUserResponse(user)}, nil
+	return &dtos.SingleUserResponse{ETag: formatETag(user.Version), Body: dtos.ToUserResponse(user)}, nil
 }
 
 func (h *UserHandler) ChangePassword(ctx context.Context, input *dtos.ChangePasswordRequest) (*struct {
@@ -157,7 +161,7 @@ func (h *UserHandler) ChangePassword(ctx context.Context, input *dtos.ChangePass
 		return nil, huma.Error401Unauthorized("User not authenticated")
 	}
 
-	err := h.userService.ChangePassword(auth.UserID, input.Body.OldPassword, input.Body.NewPassword)
+	err := h.userService.ChangePassword(auth.UserID, input.Body.OldPassword, input.Body.NewPassword, actorFromContext(ctx))
 	if err != nil {
 		return nil, huma.Error400BadRequest(err.Error())
 	}
@@ -177,15 +181,20 @@ func (h *UserHandler) GetAllUsers(ctx context.Context, input *dtos.PaginationQue
 		return nil, huma.Error403Forbidden("Only admins can list all users")
 	}
 
-	users, err := h.userService.GetAllUsers(input.Limit, input.Offset)
+	page := input.Page()
+	users, info, err := h.userService.GetAllUsers(page)
 	if err != nil {
-		return nil, huma.Error500InternalServerError(err.Error())
+		return nil, listError(err)
 	}
 
 	resp := &dtos.UserListResponse{}
 	resp.Body.Users = dtos.ToUserResponseList(users)
 	resp.Body.Limit = input.Limit
 	resp.Body.Offset = input.Offset
+	resp.Body.Total = info.Total
+	resp.Body.HasMore = info.HasMore
+	resp.Body.NextCursor = info.NextCursor
+	resp.Link = pageLinks(ctx, page, info)
 	return resp, nil
 }
 
@@ -200,7 +209,7 @@ func (h *UserHandler) GetUserByID(ctx context.Context, input *dtos.IDParam) (*dt
 		return nil, huma.Error404NotFound("User not found")
 	}
 
-	return &dtos.SingleUserResponse{Body: dtos.ToUserResponse(user)}, nil
+	return &dtos.SingleUserResponse{ETag: formatETag(user.Version), Body: dtos.ToUserResponse(user)}, nil
 }
 
 func (h *UserHandler) UpdateUser(ctx context.Context, input *dtos.UpdateUserRequest) (*dtos.SingleUserResponse, error) {
@@ -235,12 +244,20 @@ func (h *UserHandler) UpdateUser(ctx context.Context, input *dtos.UpdateUserRequ
 		role = *input.Body.Role
 	}
 
-	user, err := h.userService.UpdateUser(input.ID, email, phone, role)
+	version, err := expectedVersion(input.IfMatch, h.requireIfMatch)
 	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.userService.UpdateUser(input.ID, email, phone, role, version, actorFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrVersionMismatch) {
+			return nil, huma.Error412PreconditionFailed(err.Error())
+		}
 		return nil, huma.Error400BadRequest(err.Error())
 	}
 
-	return &dtos.SingleUserResponse{Body: dtos.ToUserResponse(user)}, nil
+	return &dtos.SingleUserResponse{ETag: formatETag(user.Version), Body: dtos.ToUserResponse(user)}, nil
 }
 
 func (h *UserHandler) DeleteUser(ctx context.Context, input *dtos.IDParam) (*dtos.EmptyResponse, error) {
@@ -255,7 +272,7 @@ func (h *UserHandler) DeleteUser(ctx context.Context, input *dtos.IDParam) (*dto
 		return nil, huma.Error400BadRequest("Cannot delete your own account")
 	}
 
-	err := h.userService.DeleteUser(input.ID)
+	err := h.userService.DeleteUser(input.ID, actorFromContext(ctx))
 	if err != nil {
 		return nil, huma.Error400BadRequest(err.Error())
 	}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// RequestInfo carries details of the incoming HTTP request to handlers
+type RequestInfo struct {
+	ID  string
+	IP  string
+	URL url.URL
+}
+
+const requestInfoKey contextKey = "request"
+
+// maxRequestIDLength bounds client-supplied X-Request-ID values
+const maxRequestIDLength = 64
+
+// RequestInfoMiddleware stores request details in the context for handlers and
+// echoes the request ID in the X-Request-ID response header. X-Forwarded-For is
+// only used for the client IP on requests coming from trustedProxies.
+func RequestInfoMiddleware(trustedProxies []netip.Prefix) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		info := &RequestInfo{
+			ID:  requestID(ctx.Header("X-Request-ID")),
+			IP:  clientIP(ctx, trustedProxies),
+			URL: ctx.URL(),
+		}
+		ctx.SetHeader("X-Request-ID", info.ID)
+		next(huma.WithContext(ctx, context.WithValue(ctx.Context(), requestInfoKey, info)))
+	}
+}
+
+// GetRequestInfo retrieves request details from context
+func GetRequestInfo(ctx context.Context) *RequestInfo {
+	if info, ok := ctx.Value(requestInfoKey).(*RequestInfo); ok {
+		return info
+	}
+	return nil
+}
+
+// requestID keeps a sane client-supplied ID so calls can be traced across services,
+// otherwise generates a new one
+func requestID(header string) string {
+	header = strings.TrimSpace(header)
+	if header != "" && len(header) <= maxRequestIDLength && !strings.ContainsAny(header, "\r\n") {
+		return header
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// clientIP returns the address the request came from. When that is a trusted
+// proxy, X-Forwarded-For is walked from the nearest hop back and the first
+// address that is not a trusted proxy is the client. Hops further back were
+// written by whoever sent the request and cannot be believed.
+func clientIP(ctx huma.Context, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(ctx.RemoteAddr())
+	if err != nil {
+		host = ctx.RemoteAddr()
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(ip, trustedProxies) {
+		return host
+	}
+
+	hops := strings.Split(ctx.Header("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func isTrustedProxy(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	ip = ip.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
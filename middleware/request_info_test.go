@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.5/32")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"spoofed header from untrusted peer", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", "198.51.100.9", "198.51.100.9"},
+		{"client-supplied hops are skipped", "10.0.0.2:5000", "1.2.3.4, 198.51.100.9", "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.2:5000", "198.51.100.9, 192.168.1.5, 10.1.1.1", "198.51.100.9"},
+		{"trusted proxy without header", "10.0.0.2:5000", "", "10.0.0.2"},
+		{"invalid hop", "10.0.0.2:5000", "198.51.100.9, garbage", "10.0.0.2"},
+		{"all hops trusted", "10.0.0.2:5000", "10.0.0.3", "10.0.0.3"},
+		{"ipv4-mapped proxy", "[::ffff:10.0.0.2]:5000", "198.51.100.9", "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			ctx := humatest.NewContext(nil, req, httptest.NewRecorder())
+
+			if got := clientIP(ctx, trusted); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.9")
+	ctx := humatest.NewContext(nil, req, httptest.NewRecorder())
+
+	if got := clientIP(ctx, nil); got != "10.0.0.2" {
+		t.Errorf("clientIP = %q, want the remote address", got)
+	}
+}
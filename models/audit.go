@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditLog is one entry of the append-only audit trail. Each entry stores the hash
+// of the previous one, so editing or deleting a row breaks the chain.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey"`
+	ActorID   *uint     `gorm:"index"`
+	ActorName string    `gorm:"size:100"`
+	Entity    string    `gorm:"not null;size:50;index:idx_audit_entity"`
+	EntityID  *uint     `gorm:"index:idx_audit_entity"`
+	Action    string    `gorm:"not null;size:50;index"`
+	Before    *string   `gorm:"type:text"`
+	After     *string   `gorm:"type:text"`
+	Changes   *string   `gorm:"type:text"`
+	RequestID string    `gorm:"size:64;index"`
+	IP        string    `gorm:"size:64"`
+	PrevHash  string    `gorm:"size:64"`
+	Hash      string    `gorm:"not null;size:64;uniqueIndex"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// ComputeHash returns the SHA-256 over the entry's content and the previous hash
+func (a *AuditLog) ComputeHash() string {
+	content, _ := json.Marshal(struct {
+		PrevHash  string  `json:"prev_hash"`
+		ActorID   *uint   `json:"actor_id"`
+		ActorName string  `json:"actor_name"`
+		Entity    string  `json:"entity"`
+		EntityID  *uint   `json:"entity_id"`
+		Action    string  `json:"action"`
+		Before    *string `json:"before"`
+		After     *string `json:"after"`
+		Changes   *string `json:"changes"`
+		RequestID string  `json:"request_id"`
+		IP        string  `json:"ip"`
+		CreatedAt string  `json:"created_at"`
+	}{
+		a.PrevHash, a.ActorID, a.ActorName, a.Entity, a.EntityID, a.Action,
+		a.Before, a.After, a.Changes, a.RequestID, a.IP,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
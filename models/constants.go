@@ -19,3 +19,59 @@ func IsValidRole(role string) bool {
 	}
 	return false
 }
+
+// Product lifecycle statuses
+const (
+	ProductStatusDraft        ProductStatus = "draft"
+	ProductStatusActive       ProductStatus = "active"
+	ProductStatusDiscontinued ProductStatus = "discontinued"
+	ProductStatusArchived     ProductStatus = "archived"
+)
+
+// Valid product statuses list
+var ValidProductStatuses = []ProductStatus{
+	ProductStatusDraft,
+	ProductStatusActive,
+	ProductStatusDiscontinued,
+	ProductStatusArchived,
+}
+
+// productStatusTransitions lists the statuses each status may move to
+var productStatusTransitions = map[ProductStatus][]ProductStatus{
+	ProductStatusDraft:        {ProductStatusActive, ProductStatusArchived},
+	ProductStatusActive:       {ProductStatusDiscontinued, ProductStatusArchived},
+	ProductStatusDiscontinued: {ProductStatusActive, ProductStatusArchived},
+	ProductStatusArchived:     {},
+}
+
+// IsValidProductStatus checks if a product status is valid
+func IsValidProductStatus(status ProductStatus) bool {
+	for _, validStatus := range ValidProductStatuses {
+		if status == validStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionProductStatus checks if a product may move from one status to another
+func CanTransitionProductStatus(from, to ProductStatus) bool {
+	for _, allowed := range productStatusTransitions[from] {
+		if to == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMovement checks if a product in the given status accepts a stock movement.
+// Discontinued products can only be sold off, archived products are frozen.
+func AllowsMovement(status ProductStatus, txType TransactionType) bool {
+	switch status {
+	case ProductStatusArchived:
+		return false
+	case ProductStatusDiscontinued:
+		return txType != TransactionTypeIn
+	}
+	return true
+}
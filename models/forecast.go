@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ProductForecast is the latest daily demand forecast of a product, replaced
+// each time forecasts are recalculated. Points and Candidates hold JSON.
+type ProductForecast struct {
+	ProductID   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Product     Product   `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Model       string    `gorm:"not null;size:50"`
+	HistoryDays int       `gorm:"not null"`
+	Points      string    `gorm:"type:text;not null"`
+	Candidates  string    `gorm:"type:text;not null"`
+	GeneratedAt time.Time `gorm:"not null"`
+}
+
+// DailyDemand is the quantity moved out of a product on one day
+type DailyDemand struct {
+	ProductID uint
+	Day       time.Time
+	Quantity  float64
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Job is a unit of background work such as a large import or export. A worker
+// claims a job by locking its row and holds a lease it renews while running; a
+// job whose lease runs out is claimed again, so a crashed replica cannot leave
+// it stuck in running.
+type Job struct {
+	ID              uint      `gorm:"primaryKey"`
+	Type            string    `gorm:"not null;size:50;index"`
+	Status          JobStatus `gorm:"not null;size:20;index:idx_jobs_claim,priority:1"`
+	Params          string    `gorm:"type:text;not null"`
+	InputKey        string    `gorm:"size:255"`
+	Progress        int       `gorm:"not null;default:0"`
+	Attempts        int       `gorm:"not null;default:0"`
+	MaxAttempts     int       `gorm:"not null;default:1"`
+	RunAt           time.Time `gorm:"not null;index:idx_jobs_claim,priority:2"`
+	LockedBy        string    `gorm:"size:100"`
+	LockedUntil     *time.Time
+	CancelRequested bool    `gorm:"not null;default:false"`
+	Error           string  `gorm:"type:text"`
+	Result          *string `gorm:"type:text"`
+	ResultKey       string  `gorm:"size:255"`
+	ResultName      string  `gorm:"size:255"`
+	ResultType      string  `gorm:"size:100"`
+	ResultSize      int64
+	CreatedBy       *uint  `gorm:"index"`
+	Creator         *User  `gorm:"foreignKey:CreatedBy;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	CreatorName     string `gorm:"size:100"`
+	RequestID       string `gorm:"size:64"`
+	IP              string `gorm:"size:64"`
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// IsFinished reports whether the job reached a final status
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
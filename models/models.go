@@ -11,18 +11,76 @@ type TransactionType string
 const (
 	TransactionTypeIn  TransactionType = "IN"
 	TransactionTypeOut TransactionType = "OUT"
+	// TransactionTypeAdjust records a quantity change made outside IN/OUT movements,
+	// such as the initial stock of a new product or an edited quantity. Its quantity
+	// is the signed change.
+	TransactionTypeAdjust TransactionType = "ADJUST"
 )
 
+type ProductStatus string
+
 type Product struct {
 	ID          uint    `gorm:"primaryKey"`
 	Name        string  `gorm:"not null;size:255"`
-	SKU         string  `gorm:"uniqueIndex;not null;size:100"`
+	SKU         string  `gorm:"uniqueIndex:idx_products_sku_active,where:deleted_at IS NULL;not null;size:100"`
 	Description string  `gorm:"type:text"`
 	Price       float64 `gorm:"type:decimal(10,2);not null"`
 	Quantity    int     `gorm:"not null;default:0"`
+	Version     uint    `gorm:"not null;default:1"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt `gorm:"index"`
+
+	Status          ProductStatus `gorm:"not null;size:20;default:active;index"`
+	StatusChangedAt *time.Time
+	StatusChangedBy *uint
+
+	CategoryID *uint `gorm:"index"`
+
+	// ABCClass is the class assigned by the last stored ABC analysis, empty until then
+	ABCClass string `gorm:"size:1;not null;default:'';index"`
+}
+
+// Category groups products in the catalogue
+type Category struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"not null;size:100;uniqueIndex"`
+	Products  []Product `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	CreatedAt time.Time
+}
+
+// ProductRevision is a snapshot of a product's fields after an update. Revisions
+// are numbered per product starting at 1.
+type ProductRevision struct {
+	ID             uint          `gorm:"primaryKey"`
+	ProductID      uint          `gorm:"not null;uniqueIndex:idx_product_revisions_number"`
+	Product        Product       `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Revision       uint          `gorm:"not null;uniqueIndex:idx_product_revisions_number"`
+	ProductVersion uint          `gorm:"not null"`
+	Name           string        `gorm:"not null;size:255"`
+	SKU            string        `gorm:"not null;size:100"`
+	Description    string        `gorm:"type:text"`
+	Price          float64       `gorm:"type:decimal(10,2);not null"`
+	Quantity       int           `gorm:"not null"`
+	Status         ProductStatus `gorm:"not null;size:20"`
+	CategoryID     *uint
+	ChangedBy      *uint
+	CreatedAt      time.Time
+}
+
+// NewProductRevision snapshots the current state of product
+func NewProductRevision(product *Product) *ProductRevision {
+	return &ProductRevision{
+		ProductID:      product.ID,
+		ProductVersion: product.Version,
+		Name:           product.Name,
+		SKU:            product.SKU,
+		Description:    product.Description,
+		Price:          product.Price,
+		Quantity:       product.Quantity,
+		Status:         product.Status,
+		CategoryID:     product.CategoryID,
+	}
 }
 
 type Transaction struct {
@@ -31,11 +89,57 @@ type Transaction struct {
 	Product         Product         `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
 	Quantity        int             `gorm:"not null"`
 	TransactionType TransactionType `gorm:"not null;size:10"`
+	Reference       string          `gorm:"size:100;index"`
 	Notes           string          `gorm:"type:text"`
+	CreatedBy       *uint           `gorm:"index"`
+	Creator         *User           `gorm:"foreignKey:CreatedBy;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
 
+// TransactionLedgerRow is a transaction with the product and user it refers to,
+// as written to ledger exports
+type TransactionLedgerRow struct {
+	Transaction Transaction `gorm:"embedded"`
+	ProductSKU  string
+	ProductName string
+	CreatorName string
+}
+
+// ProductSearchResult is a product matched by full-text search with its relevance
+type ProductSearchResult struct {
+	Product   Product `gorm:"embedded"`
+	Rank      float64
+	Highlight string
+}
+
+// ProductImage is an uploaded photo of a product; the file lives in storage
+type ProductImage struct {
+	ID           uint    `gorm:"primaryKey"`
+	ProductID    uint    `gorm:"not null;index"`
+	Product      Product `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	FileName     string  `gorm:"not null;size:255"`
+	ContentType  string  `gorm:"not null;size:100"`
+	Size         int64   `gorm:"not null"`
+	StorageKey   string  `gorm:"not null;size:255"`
+	ThumbnailKey string  `gorm:"size:255"`
+	UploadedBy   uint    `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// TransactionAttachment is a file such as a delivery note attached to a transaction
+type TransactionAttachment struct {
+	ID            uint        `gorm:"primaryKey"`
+	TransactionID uint        `gorm:"not null;index"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	FileName      string      `gorm:"not null;size:255"`
+	ContentType   string      `gorm:"not null;size:100"`
+	Size          int64       `gorm:"not null"`
+	StorageKey    string      `gorm:"not null;size:255"`
+	UploadedBy    uint        `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
 type User struct {
 	ID             uint   `gorm:"primaryKey"`
 	Username       string `gorm:"not null;unique"`
@@ -43,4 +147,19 @@ type User struct {
 	Email          string `gorm:"not null;unique"`
 	Role           string `gorm:"not null"`
 	Phone          string `gorm:"not null"`
+	Version        uint   `gorm:"not null;default:1"`
+}
+
+// IdempotencyKey stores the outcome of a mutating request so retries with the
+// same Idempotency-Key header replay the original response instead of re-executing
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_idempotency_scope"`
+	Operation    string    `gorm:"not null;size:100;uniqueIndex:idx_idempotency_scope"`
+	Key          string    `gorm:"not null;size:255;uniqueIndex:idx_idempotency_scope"`
+	RequestHash  string    `gorm:"not null;size:64"`
+	ResponseBody string    `gorm:"type:text"`
+	Completed    bool      `gorm:"not null;default:false"`
+	ExpiresAt    time.Time `gorm:"not null;index"` // end of the lease while pending, of the replay once completed
+	CreatedAt    time.Time
 }
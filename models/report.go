@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// UserMovement totals the stock movements recorded by one user
+type UserMovement struct {
+	UserID      *uint
+	Username    string
+	InCount     int64
+	InQuantity  int64
+	OutCount    int64
+	OutQuantity int64
+	LastMovedAt time.Time
+}
+
+// ProductConsumption is the value of one product's OUT movements in a window
+type ProductConsumption struct {
+	ProductID        uint
+	SKU              string
+	Name             string
+	Price            float64
+	OutQuantity      int64
+	ConsumptionValue float64
+}
+
+// ProductStockActivity is a product with stock on hand and when it last moved
+type ProductStockActivity struct {
+	ProductID  uint
+	SKU        string
+	Name       string
+	CategoryID *uint
+	Price      float64
+	Quantity   int
+	CreatedAt  time.Time
+	LastInAt   *time.Time
+	LastOutAt  *time.Time
+}
+
+// StockLayer is the part of a receipt still on hand, assuming stock leaves
+// first in, first out
+type StockLayer struct {
+	ProductID  uint
+	ReceivedAt time.Time
+	Quantity   int64
+}
+
+// ProductBalance is the stock of one product at a point in time, rebuilt from the ledger
+type ProductBalance struct {
+	ProductID uint
+	SKU       string
+	Name      string
+	Balance   int64
+}
+
+// ProductPeriodMovement totals the ledger rows of one product in one period
+type ProductPeriodMovement struct {
+	ProductID   uint
+	SKU         string
+	Name        string
+	Period      time.Time
+	InQuantity  int64
+	OutQuantity int64
+	Adjustments int64
+}
+
+// StockSummary totals the stock of all products
+type StockSummary struct {
+	ProductCount  int64
+	TotalQuantity int64
+	TotalValue    float64
+	StockoutCount int64
+	LowStockCount int64
+}
+
+// MovementValueTotals totals the ledger since a point in time, valued at current prices
+type MovementValueTotals struct {
+	OutQuantity int64
+	OutValue    float64
+	NetValue    float64
+}
+
+// ProductMover is a product with the stock moved out of it
+type ProductMover struct {
+	ProductID   uint
+	SKU         string
+	Name        string
+	OutQuantity int64
+	OutValue    float64
+}
+
+// DailyMovement totals the IN and OUT quantities of one day
+type DailyMovement struct {
+	Day         time.Time
+	InQuantity  int64
+	OutQuantity int64
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"inventory-api/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db             *gorm.DB
+	imageRepo      *BaseRepository[models.ProductImage]
+	attachmentRepo *BaseRepository[models.TransactionAttachment]
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{
+		db:             db,
+		imageRepo:      NewBaseRepository[models.ProductImage](db),
+		attachmentRepo: NewBaseRepository[models.TransactionAttachment](db),
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *AttachmentRepository) WithTx(tx *gorm.DB) *AttachmentRepository {
+	return NewAttachmentRepository(tx)
+}
+
+// Product image operations
+func (r *AttachmentRepository) CreateProductImage(image *models.ProductImage) error {
+	return r.imageRepo.Create(context.Background(), image)
+}
+
+func (r *AttachmentRepository) GetProductImage(productID, imageID uint) (*models.ProductImage, error) {
+	return r.imageRepo.FindOne(
+		context.Background(),
+		WithWhere("id = ? AND product_id = ?", imageID, productID),
+	)
+}
+
+func (r *AttachmentRepository) GetProductImages(productID uint) ([]models.ProductImage, error) {
+	return r.imageRepo.List(
+		context.Background(),
+		WithWhere("product_id = ?", productID),
+		WithOrder("id ASC"),
+	)
+}
+
+func (r *AttachmentRepository) DeleteProductImage(id uint) error {
+	return r.imageRepo.Delete(context.Background(), id)
+}
+
+// Transaction attachment operations
+func (r *AttachmentRepository) CreateTransactionAttachment(attachment *models.TransactionAttachment) error {
+	return r.attachmentRepo.Create(context.Background(), attachment)
+}
+
+func (r *AttachmentRepository) GetTransactionAttachment(transactionID, attachmentID uint) (*models.TransactionAttachment, error) {
+	return r.attachmentRepo.FindOne(
+		context.Background(),
+		WithWhere("id = ? AND transaction_id = ?", attachmentID, transactionID),
+	)
+}
+
+func (r *AttachmentRepository) GetTransactionAttachments(transactionID uint) ([]models.TransactionAttachment, error) {
+	return r.attachmentRepo.List(
+		context.Background(),
+		WithWhere("transaction_id = ?", transactionID),
+		WithOrder("id ASC"),
+	)
+}
+
+func (r *AttachmentRepository) DeleteTransactionAttachment(id uint) error {
+	return r.attachmentRepo.Delete(context.Background(), id)
+}
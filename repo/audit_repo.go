@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditChainLock is the advisory lock key serializing appends to the hash chain
+const auditChainLock = 0x6175646974
+
+type AuditRepository struct {
+	db        *gorm.DB
+	auditRepo *BaseRepository[models.AuditLog]
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{
+		db:        db,
+		auditRepo: NewBaseRepository[models.AuditLog](db),
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *AuditRepository) WithTx(tx *gorm.DB) *AuditRepository {
+	return NewAuditRepository(tx)
+}
+
+// Transaction runs fn in a database transaction, committing if it returns nil
+func (r *AuditRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(context.Background()).Transaction(fn)
+}
+
+// Append links entries to the latest entry's hash in order and inserts them. Appends
+// are serialized with an advisory lock so concurrent writers cannot fork the chain.
+// The lock is held until the enclosing transaction ends, so appending in a longer
+// transaction should be its last statement.
+func (r *AuditRepository) Append(entries ...*models.AuditLog) error {
+	return r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditLog
+		err := tx.Select("hash").Order("id DESC").Take(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		prevHash := last.Hash
+		for _, entry := range entries {
+			entry.PrevHash = prevHash
+			entry.Hash = entry.ComputeHash()
+			prevHash = entry.Hash
+		}
+		return tx.Create(entries).Error
+	})
+}
+
+// GetAuditLogs retrieves a page of entries, newest first
+func (r *AuditRepository) GetAuditLogs(filter *dtos.AuditFilter, page dtos.PageRequest) ([]models.AuditLog, dtos.PageInfo, error) {
+	scopes := buildAuditFilterScopes(filter)
+	scopes = append(scopes, WithOffset(page.Offset))
+
+	return r.auditRepo.ListPage(
+		context.Background(),
+		[]clause.OrderByColumn{{Column: clause.Column{Table: "audit_log", Name: "id"}, Desc: true}},
+		page.Cursor,
+		page.Limit,
+		scopes...,
+	)
+}
+
+func (r *AuditRepository) CountAuditLogs(filter *dtos.AuditFilter) (int64, error) {
+	return r.auditRepo.Count(context.Background(), buildAuditFilterScopes(filter)...)
+}
+
+// EachAuditLog calls fn for every matching entry in chain order without loading
+// them all into memory
+func (r *AuditRepository) EachAuditLog(filter *dtos.AuditFilter, fn func(*models.AuditLog) error) error {
+	db := r.db.WithContext(context.Background())
+	rows, err := db.Model(&models.AuditLog{}).
+		Scopes(buildAuditFilterScopes(filter)...).
+		Order("id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// buildAuditFilterScopes converts AuditFilter to GORM scopes
+func buildAuditFilterScopes(filter *dtos.AuditFilter) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{}
+
+	if filter == nil {
+		return scopes
+	}
+
+	if filter.ActorID != nil {
+		scopes = append(scopes, WithWhere("actor_id = ?", *filter.ActorID))
+	}
+	if filter.Entity != nil {
+		scopes = append(scopes, WithWhere("entity = ?", *filter.Entity))
+	}
+	if filter.EntityID != nil {
+		scopes = append(scopes, WithWhere("entity_id = ?", *filter.EntityID))
+	}
+	if filter.Action != nil {
+		scopes = append(scopes, WithWhere("action = ?", *filter.Action))
+	}
+	if filter.RequestID != nil {
+		scopes = append(scopes, WithWhere("request_id = ?", *filter.RequestID))
+	}
+	if filter.From != nil {
+		scopes = append(scopes, WithWhere("created_at >= ?", *filter.From))
+	}
+	if filter.To != nil {
+		scopes = append(scopes, WithWhere("created_at <= ?", *filter.To))
+	}
+
+	return scopes
+}
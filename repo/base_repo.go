@@ -2,10 +2,18 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"inventory-api/dtos"
+	"reflect"
+	"slices"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned when an optimistic update finds the row's version has moved on
+var ErrVersionConflict = errors.New("record was modified concurrently")
+
 type BaseRepository[T any] struct {
 	db *gorm.DB
 }
@@ -31,6 +39,58 @@ func (r *BaseRepository[T]) Update(ctx context.Context, entity *T) error {
 	return r.db.WithContext(ctx).Save(entity).Error
 }
 
+// UpdateChanged writes the columns that differ between previous and entity and bumps
+// the version. The row is locked first and every column entity did not change is
+// taken from it, so concurrent updates to those columns are kept rather than
+// overwritten with stale values. With a non-zero expectedVersion it fails with
+// ErrVersionConflict unless the row is still at that version. On success previous
+// holds the row as it was before the update and entity the row as saved. Columns in
+// omit are never written and always taken from the row.
+func (r *BaseRepository[T]) UpdateChanged(ctx context.Context, entity, previous *T, expectedVersion uint, omit ...string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(entity); err != nil {
+			return err
+		}
+		updated := reflect.ValueOf(entity).Elem()
+		before := reflect.ValueOf(previous).Elem()
+
+		var current T
+		id := stmt.Schema.PrioritizedPrimaryField.ReflectValueOf(ctx, updated).Interface()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, id).Error; err != nil {
+			return err
+		}
+		row := reflect.ValueOf(&current).Elem()
+
+		version := stmt.Schema.LookUpField("Version")
+		currentVersion := version.ReflectValueOf(ctx, row).Uint()
+		if expectedVersion != 0 && currentVersion != uint64(expectedVersion) {
+			return ErrVersionConflict
+		}
+
+		columns := []string{version.DBName}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.PrimaryKey || field == version {
+				continue
+			}
+			value := field.ReflectValueOf(ctx, updated)
+			old := field.ReflectValueOf(ctx, before)
+			stored := field.ReflectValueOf(ctx, row)
+			if field.Updatable && field.AutoCreateTime == 0 && field.AutoUpdateTime == 0 &&
+				!slices.Contains(omit, field.DBName) && !reflect.DeepEqual(value.Interface(), old.Interface()) {
+				columns = append(columns, field.DBName)
+			} else {
+				value.Set(stored)
+			}
+			old.Set(stored)
+		}
+		version.ReflectValueOf(ctx, before).SetUint(currentVersion)
+		version.ReflectValueOf(ctx, updated).SetUint(currentVersion + 1)
+
+		return tx.Model(entity).Select(columns).Updates(entity).Error
+	})
+}
+
 func (r *BaseRepository[T]) Delete(ctx context.Context, id uint) error {
 	var entity T
 	return r.db.WithContext(ctx).Delete(&entity, id).Error
@@ -42,6 +102,13 @@ func (r *BaseRepository[T]) List(ctx context.Context, scopes ...func(*gorm.DB) *
 	return results, err
 }
 
+// ListPage returns up to limit rows in the given order starting after cursor, and
+// whether more rows follow along with the cursor of the next page. Unlike offsets,
+// cursors do not skip or repeat rows when new rows are inserted between requests.
+func (r *BaseRepository[T]) ListPage(ctx context.Context, order []clause.OrderByColumn, cursor string, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]T, dtos.PageInfo, error) {
+	return paginate[T](r.db.WithContext(ctx).Scopes(scopes...), order, cursor, limit)
+}
+
 func (r *BaseRepository[T]) Count(ctx context.Context, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
 	var count int64
 	var entity T
@@ -85,9 +152,50 @@ func WithPreload(associations ...string) func(*gorm.DB) *gorm.DB {
 	}
 }
 
+// WithOrderBy orders by the given columns, quoting column names
+func WithOrderBy(columns ...clause.OrderByColumn) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderBy{Columns: columns})
+	}
+}
+
+// WithUnscoped includes soft-deleted records
+func WithUnscoped() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
+
+// WithPreloadUnscoped preloads an association including soft-deleted records
+func WithPreloadUnscoped(association string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload(association, func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		})
+	}
+}
+
 // WithWhere adds a WHERE condition
 func WithWhere(query interface{}, args ...interface{}) func(*gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where(query, args...)
 	}
 }
+
+// WithSelect loads only the given columns of table; no columns loads them all
+func WithSelect(table string, columns ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(columns) == 0 {
+			return db
+		}
+		return db.Select(qualifyColumns(table, columns))
+	}
+}
+
+func qualifyColumns(table string, columns []string) []string {
+	qualified := make([]string, len(columns))
+	for i, column := range columns {
+		qualified[i] = table + "." + column
+	}
+	return qualified
+}
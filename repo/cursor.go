@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or
+// was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// cursorPayload is the JSON inside an opaque cursor: the sort key values of the
+// last row of a page and the sort order they belong to
+type cursorPayload struct {
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+// paginate runs query ordered by order and returns up to limit rows after cursor.
+// When more rows follow, the page info carries the cursor for the next page. The
+// last order column must be unique so the position of every row is well defined.
+func paginate[T any](query *gorm.DB, order []clause.OrderByColumn, cursor string, limit int) ([]T, dtos.PageInfo, error) {
+	signature := orderSignature(order)
+
+	if cursor != "" {
+		values, err := decodeCursor(cursor, signature, len(order))
+		if err != nil {
+			return nil, dtos.PageInfo{}, err
+		}
+		query = query.Where(keysetCondition(order, values))
+	}
+
+	results, info, err := findPage[T](query.Order(clause.OrderBy{Columns: order}), limit)
+	if err != nil || !info.HasMore {
+		return results, info, err
+	}
+
+	info.NextCursor, err = encodeCursor(query, &results[limit-1], order, signature)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+	return results, info, nil
+}
+
+// findPage fetches one extra row to know whether another page exists
+func findPage[T any](query *gorm.DB, limit int) ([]T, dtos.PageInfo, error) {
+	var results []T
+	if err := query.Limit(limit + 1).Find(&results).Error; err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+	if len(results) <= limit {
+		return results, dtos.PageInfo{}, nil
+	}
+	return results[:limit], dtos.PageInfo{HasMore: true}, nil
+}
+
+// keysetCondition builds "rows after values" for a mixed ASC/DESC order:
+// (a > va) OR (a = va AND b > vb) OR (a = va AND b = vb AND c > vc) ...
+func keysetCondition(order []clause.OrderByColumn, values []interface{}) clause.Expression {
+	branches := make([]clause.Expression, 0, len(order))
+	for i, column := range order {
+		conditions := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, clause.Eq{Column: order[j].Column, Value: values[j]})
+		}
+		if column.Desc {
+			conditions = append(conditions, clause.Lt{Column: column.Column, Value: values[i]})
+		} else {
+			conditions = append(conditions, clause.Gt{Column: column.Column, Value: values[i]})
+		}
+		branches = append(branches, clause.And(conditions...))
+	}
+	return clause.Or(branches...)
+}
+
+// encodeCursor reads the order column values from row and packs them into a cursor
+func encodeCursor(db *gorm.DB, row interface{}, order []clause.OrderByColumn, signature string) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return "", err
+	}
+
+	rowValue := reflect.ValueOf(row).Elem()
+	values := make([]interface{}, len(order))
+	for i, column := range order {
+		field := stmt.Schema.LookUpField(column.Column.Name)
+		if field == nil {
+			return "", fmt.Errorf("cannot build cursor: unknown column %q", column.Column.Name)
+		}
+		values[i], _ = field.ValueOf(context.Background(), rowValue)
+	}
+
+	data, err := json.Marshal(cursorPayload{Order: signature, Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor, signature string, size int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// Keep numbers as json.Number so large IDs and prices are not rounded
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Order != signature || len(payload.Values) != size {
+		return nil, ErrInvalidCursor
+	}
+	for i, value := range payload.Values {
+		if number, ok := value.(json.Number); ok {
+			payload.Values[i] = number.String()
+		}
+	}
+	return payload.Values, nil
+}
+
+// orderSignature identifies a sort order so cursors cannot be reused across orders
+func orderSignature(order []clause.OrderByColumn) string {
+	parts := make([]string, len(order))
+	for i, column := range order {
+		direction := "asc"
+		if column.Desc {
+			direction = "desc"
+		}
+		parts[i] = column.Column.Table + "." + column.Column.Name + ":" + direction
+	}
+	return strings.Join(parts, ",")
+}
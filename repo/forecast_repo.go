@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"inventory-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ForecastRepository struct {
+	db *gorm.DB
+}
+
+func NewForecastRepository(db *gorm.DB) *ForecastRepository {
+	return &ForecastRepository{db: db}
+}
+
+func (r *ForecastRepository) GetProductByID(id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(context.Background()).First(&product, id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// CountForecastProducts counts the products GetForecastProducts walks through
+func (r *ForecastRepository) CountForecastProducts(ids []uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Scopes(buildForecastProductScopes(ids)...).
+		Count(&count).Error
+	return count, err
+}
+
+// GetForecastProducts returns the next limit products after afterID, by ID, that
+// are not archived, optionally only those in ids
+func (r *ForecastRepository) GetForecastProducts(ids []uint, afterID uint, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.WithContext(context.Background()).
+		Scopes(buildForecastProductScopes(ids)...).
+		Where("products.id > ?", afterID).
+		Order("products.id").
+		Limit(limit).
+		Find(&products).Error
+	return products, err
+}
+
+func buildForecastProductScopes(ids []uint) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{
+		WithWhere("products.status <> ?", models.ProductStatusArchived),
+	}
+	if len(ids) > 0 {
+		scopes = append(scopes, WithWhere("products.id IN ?", ids))
+	}
+	return scopes
+}
+
+// GetDailyDemand totals the OUT quantity of each of the products per UTC day in
+// [from, to). Days without OUT movements are left out.
+func (r *ForecastRepository) GetDailyDemand(productIDs []uint, from, to time.Time) ([]models.DailyDemand, error) {
+	var results []models.DailyDemand
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`transactions.product_id,
+			date_trunc('day', transactions.created_at AT TIME ZONE 'UTC') AS day,
+			SUM(transactions.quantity) AS quantity`).
+		Where("transactions.product_id IN ? AND transactions.transaction_type = ?", productIDs, models.TransactionTypeOut).
+		Where("transactions.created_at >= ? AND transactions.created_at < ?", from, to).
+		Group("transactions.product_id, day").
+		Order("transactions.product_id, day").
+		Scan(&results).Error
+	return results, err
+}
+
+func (r *ForecastRepository) GetForecast(productID uint) (*models.ProductForecast, error) {
+	var forecast models.ProductForecast
+	err := r.db.WithContext(context.Background()).
+		Where("product_id = ?", productID).
+		First(&forecast).Error
+	if err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+// SaveForecasts replaces the stored forecasts of the products
+func (r *ForecastRepository) SaveForecasts(forecasts []models.ProductForecast) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+	return r.db.WithContext(context.Background()).
+		Omit("Product").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"model", "history_days", "points", "candidates", "generated_at"}),
+		}).
+		Create(&forecasts).Error
+}
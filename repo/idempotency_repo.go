@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"inventory-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db      *gorm.DB
+	keyRepo *BaseRepository[models.IdempotencyKey]
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:      db,
+		keyRepo: NewBaseRepository[models.IdempotencyKey](db),
+	}
+}
+
+// ReserveKey inserts a pending key record. It returns false without error when
+// the key already exists for the same user and operation.
+func (r *IdempotencyRepository) ReserveKey(key *models.IdempotencyKey) (bool, error) {
+	result := r.db.WithContext(context.Background()).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *IdempotencyRepository) GetKey(userID uint, operation, key string) (*models.IdempotencyKey, error) {
+	return r.keyRepo.FindOne(context.Background(), func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND operation = ? AND key = ?", userID, operation, key)
+	})
+}
+
+// RenewKey extends the lease on a pending key record until expiresAt. It returns
+// false without error when the record is gone or already completed, i.e. the lease
+// ran out and a retry reclaimed the key.
+func (r *IdempotencyRepository) RenewKey(id uint, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(context.Background()).
+		Model(&models.IdempotencyKey{}).
+		Where("id = ? AND completed = ?", id, false).
+		Update("expires_at", expiresAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompleteKey stores the response body, marks the pending key record as completed
+// and keeps it until expiresAt. Like RenewKey it returns false without error when
+// the record is no longer pending.
+func (r *IdempotencyRepository) CompleteKey(id uint, responseBody string, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(context.Background()).
+		Model(&models.IdempotencyKey{}).
+		Where("id = ? AND completed = ?", id, false).
+		Updates(map[string]interface{}{
+			"response_body": responseBody,
+			"completed":     true,
+			"expires_at":    expiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *IdempotencyRepository) DeleteKey(id uint) error {
+	return r.keyRepo.Delete(context.Background(), id)
+}
+
+// DeleteExpiredKey removes an expired record for the given key so it can be reused
+func (r *IdempotencyRepository) DeleteExpiredKey(userID uint, operation, key string, now time.Time) error {
+	return r.db.WithContext(context.Background()).
+		Where("user_id = ? AND operation = ? AND key = ? AND expires_at < ?", userID, operation, key, now).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes all expired key records and returns how many were deleted
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.WithContext(context.Background()).
+		Where("expires_at < ?", now).
+		Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -2,16 +2,30 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"inventory-api/dtos"
 	"inventory-api/models"
+	"slices"
+	"sort"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrMovementNotAllowed is returned when the product's status rejects a stock movement
+var ErrMovementNotAllowed = errors.New("product status does not allow this movement")
+
+// ErrProductHasMovements is returned when purging a product that has IN or OUT transactions
+var ErrProductHasMovements = errors.New("product has stock movements")
+
 type InventoryRepository struct {
 	db              *gorm.DB
 	productRepo     *BaseRepository[models.Product]
 	transactionRepo *BaseRepository[models.Transaction]
+	revisionRepo    *BaseRepository[models.ProductRevision]
+	categoryRepo    *BaseRepository[models.Category]
 }
 
 func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
@@ -19,12 +33,28 @@ func NewInventoryRepository(db *gorm.DB) *InventoryRepository {
 		db:              db,
 		productRepo:     NewBaseRepository[models.Product](db),
 		transactionRepo: NewBaseRepository[models.Transaction](db),
+		revisionRepo:    NewBaseRepository[models.ProductRevision](db),
+		categoryRepo:    NewBaseRepository[models.Category](db),
 	}
 }
 
+// WithTx returns a copy of the repository that runs its queries in tx. Its own
+// transactions become savepoints of tx.
+func (r *InventoryRepository) WithTx(tx *gorm.DB) *InventoryRepository {
+	return NewInventoryRepository(tx)
+}
+
 // Product operations using BaseRepository
-func (r *InventoryRepository) CreateProduct(product *models.Product) error {
-	return r.productRepo.Create(context.Background(), product)
+// CreateProduct creates the product and records its initial quantity as an
+// adjustment in the same transaction
+func (r *InventoryRepository) CreateProduct(product *models.Product, createdBy uint) error {
+	ctx := context.Background()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := NewBaseRepository[models.Product](tx).Create(ctx, product); err != nil {
+			return err
+		}
+		return createAdjustments(tx, newAdjustment(product.ID, product.Quantity, adjustmentNotesInitial, createdBy))
+	})
 }
 
 func (r *InventoryRepository) GetProductByID(id uint) (*models.Product, error) {
@@ -45,14 +75,80 @@ func (r *InventoryRepository) GetAllProducts(limit, offset int) ([]models.Produc
 	)
 }
 
-// GetProductsWithFilter retrieves products with filtering support
-func (r *InventoryRepository) GetProductsWithFilter(filter *dtos.ProductFilter, limit, offset int) ([]models.Product, error) {
+// GetProductByIDWithFields retrieves a product loading only the projected columns.
+// The version is always loaded so callers can emit an ETag.
+func (r *InventoryRepository) GetProductByIDWithFields(id uint, projection dtos.Projection) (*models.Product, error) {
+	return r.productRepo.FindOne(
+		context.Background(),
+		WithWhere("id = ?", id),
+		WithSelect("products", selectedColumns(projection, "version")...),
+	)
+}
+
+// GetProductsWithFilter retrieves a page of products with filtering and sorting support
+func (r *InventoryRepository) GetProductsWithFilter(filter *dtos.ProductFilter, sort []dtos.SortField, page dtos.PageRequest, projection dtos.Projection) ([]models.Product, dtos.PageInfo, error) {
+	order := sortColumns("products", sort, clause.OrderByColumn{Column: clause.Column{Table: "products", Name: "id"}})
+
 	scopes := r.buildProductFilterScopes(filter)
+	scopes = append(scopes,
+		WithSelect("products", selectedColumns(projection, orderColumnNames(order)...)...),
+		WithOffset(page.Offset),
+	)
 
-	// Add pagination
-	scopes = append(scopes, WithLimit(limit), WithOffset(offset))
+	return r.productRepo.ListPage(context.Background(), order, page.Cursor, page.Limit, scopes...)
+}
 
-	return r.productRepo.List(context.Background(), scopes...)
+// CountProductsWithFilter counts all products matching filter, ignoring pagination
+func (r *InventoryRepository) CountProductsWithFilter(filter *dtos.ProductFilter) (int64, error) {
+	return r.productRepo.Count(context.Background(), r.buildProductFilterScopes(filter)...)
+}
+
+// EachProduct calls fn for every product matching filter in ID order, reading
+// from a single snapshot through a database cursor
+func (r *InventoryRepository) EachProduct(filter *dtos.ProductFilter, fn func(*models.Product) error) error {
+	scopes := r.buildProductFilterScopes(filter)
+	return streamSnapshot(r.db, func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.Product{}).Scopes(scopes...).Order("products.id ASC")
+	}, fn)
+}
+
+// highlightSource is the product text snippets are cut from. It is HTML-escaped
+// before ts_headline runs, so the <mark> tags it adds are the only markup in a
+// highlight and product names cannot inject HTML into pages rendering it.
+const highlightSource = `replace(replace(replace(replace(replace(
+	products.name || ' ' || coalesce(products.description, ''),
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+
+// SearchProducts runs a full-text search combined with trigram similarity so
+// typos still match. Results are ordered by relevance with highlighted snippets,
+// unless sort is given, in which case the results can also be paged by cursor.
+func (r *InventoryRepository) SearchProducts(filter *dtos.ProductFilter, sort []dtos.SortField, page dtos.PageRequest, projection dtos.Projection) ([]models.ProductSearchResult, dtos.PageInfo, error) {
+	query := *filter.Query
+	scopes := r.buildProductFilterScopes(filter)
+	order := sortColumns("products", sort, clause.OrderByColumn{Column: clause.Column{Table: "products", Name: "id"}})
+
+	columns := "products.*"
+	if projection.Fields != nil {
+		columns = strings.Join(qualifyColumns("products", selectedColumns(projection, orderColumnNames(order)...)), ", ")
+	}
+
+	db := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(columns+`,
+			ts_rank_cd(products.search_vector, websearch_to_tsquery('simple', ?)) + similarity(products.name, ?) AS rank,
+			ts_headline('simple', `+highlightSource+`,
+				websearch_to_tsquery('simple', ?),
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') AS highlight`,
+			query, query, query).
+		Scopes(scopes...).
+		Scopes(WithOffset(page.Offset))
+
+	// Explicit sort fields take precedence over relevance
+	if len(sort) > 0 {
+		return paginate[models.ProductSearchResult](db, order, page.Cursor, page.Limit)
+	}
+
+	return findPage[models.ProductSearchResult](db.Order("rank DESC, products.id ASC"), page.Limit)
 }
 
 // buildProductFilterScopes converts ProductFilter to GORM scopes
@@ -63,6 +159,46 @@ func (r *InventoryRepository) buildProductFilterScopes(filter *dtos.ProductFilte
 		return scopes
 	}
 
+	// Include soft-deleted products
+	if filter.IncludeDeleted {
+		scopes = append(scopes, WithUnscoped())
+	}
+
+	// Full-text search, falling back to trigram similarity for typos
+	if filter.HasQuery() {
+		query := *filter.Query
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where(
+				"(products.search_vector @@ websearch_to_tsquery('simple', ?) OR products.name % ? OR products.sku % ?)",
+				query, query, query,
+			)
+		})
+	}
+
+	// Filter by lifecycle status
+	if filter.HasStatus() {
+		status := *filter.Status
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("status = ?", status)
+		})
+	}
+
+	// Filter by category
+	if filter.HasCategoryID() {
+		categoryID := *filter.CategoryID
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.category_id = ?", categoryID)
+		})
+	}
+
+	// Filter by ABC class
+	if filter.HasABCClass() {
+		abcClass := *filter.ABCClass
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.abc_class = ?", abcClass)
+		})
+	}
+
 	// Filter by SKU (exact match)
 	if filter.HasSKU() {
 		sku := *filter.SKU
@@ -95,85 +231,760 @@ func (r *InventoryRepository) buildProductFilterScopes(filter *dtos.ProductFilte
 		})
 	}
 
+	// Filter by creation date range
+	if filter.HasCreatedFrom() {
+		createdFrom := *filter.CreatedFrom
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.created_at >= ?", createdFrom)
+		})
+	}
+	if filter.HasCreatedTo() {
+		createdTo := *filter.CreatedTo
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.created_at <= ?", createdTo)
+		})
+	}
+
+	// Filter by stock quantity range
+	if filter.HasMinQty() {
+		minQty := *filter.MinQty
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.quantity >= ?", minQty)
+		})
+	}
+	if filter.HasMaxQty() {
+		maxQty := *filter.MaxQty
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("products.quantity <= ?", maxQty)
+		})
+	}
+
 	return scopes
 }
 
-func (r *InventoryRepository) UpdateProduct(product *models.Product) error {
-	return r.productRepo.Update(context.Background(), product)
+// selectedColumns returns the projected columns plus the ones a query needs
+// internally, or nil to load every column
+func selectedColumns(projection dtos.Projection, required ...string) []string {
+	if projection.Fields == nil {
+		return nil
+	}
+	columns := append([]string{}, projection.Fields...)
+	for _, column := range required {
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// orderColumnNames lists the columns of an order so cursors can be built from selected rows
+func orderColumnNames(order []clause.OrderByColumn) []string {
+	names := make([]string, len(order))
+	for i, column := range order {
+		names[i] = column.Column.Name
+	}
+	return names
+}
+
+// transactionProjectionScopes selects the projected transaction columns and
+// preloads expanded relations
+func transactionProjectionScopes(projection dtos.Projection, required ...string) []func(*gorm.DB) *gorm.DB {
+	var scopes []func(*gorm.DB) *gorm.DB
+	if projection.Expands("product") {
+		required = append(required, "product_id")
+		scopes = append(scopes, WithPreloadUnscoped("Product"))
+	}
+	if projection.Expands("user") {
+		required = append(required, "created_by")
+		scopes = append(scopes, WithPreload("Creator"))
+	}
+	return append(scopes, WithSelect("transactions", selectedColumns(projection, required...)...))
+}
+
+// sortColumns converts whitelisted sort fields to order columns and always ends
+// with a unique tiebreaker so offset and cursor pagination are stable
+func sortColumns(table string, sort []dtos.SortField, tiebreaker clause.OrderByColumn) []clause.OrderByColumn {
+	columns := make([]clause.OrderByColumn, 0, len(sort)+1)
+	for _, field := range sort {
+		if field.Field == tiebreaker.Column.Name {
+			tiebreaker.Desc = field.Desc
+			continue
+		}
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Table: table, Name: field.Field},
+			Desc:   field.Desc,
+		})
+	}
+	return append(columns, tiebreaker)
+}
+
+// abcClassColumn is written only by the ABC analysis. Product updates omit it, so
+// one loaded before an analysis never saves the stale class back.
+const abcClassColumn = "abc_class"
+
+// UpdateProduct saves the columns of product that differ from previous and bumps
+// its version. With a non-zero expectedVersion it fails with ErrVersionConflict if
+// the product is no longer at that version; otherwise the changes are applied on
+// top of the product's current state.
+func (r *InventoryRepository) UpdateProduct(product, previous *models.Product, expectedVersion uint) error {
+	return r.productRepo.UpdateChanged(context.Background(), product, previous, expectedVersion, abcClassColumn)
+}
+
+// UpdateProductWithRevision updates the product like UpdateProduct and stores its new
+// state as the next revision in the same transaction. previous is the state before
+// the update; it becomes revision 1 for products edited before revisions existed.
+func (r *InventoryRepository) UpdateProductWithRevision(product, previous *models.Product, expectedVersion, changedBy uint) (*models.ProductRevision, error) {
+	ctx := context.Background()
+	var revision *models.ProductRevision
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The update locks the product row, so revision numbers cannot race
+		if err := NewBaseRepository[models.Product](tx).UpdateChanged(ctx, product, previous, expectedVersion, abcClassColumn); err != nil {
+			return err
+		}
+
+		var err error
+		revision, err = saveRevision(tx, product, previous, changedBy)
+		if err != nil {
+			return err
+		}
+		return createAdjustments(tx, newAdjustment(product.ID, product.Quantity-previous.Quantity, adjustmentNotesEdited, changedBy))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// saveRevision stores the updated product as its next revision, first storing
+// previous as revision 1 when the product has none yet
+func saveRevision(tx *gorm.DB, product, previous *models.Product, changedBy uint) (*models.ProductRevision, error) {
+	var latest uint
+	if err := tx.Model(&models.ProductRevision{}).
+		Where("product_id = ?", product.ID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	if latest == 0 {
+		baseline := models.NewProductRevision(previous)
+		baseline.Revision = 1
+		baseline.CreatedAt = previous.UpdatedAt
+		if err := tx.Omit("Product").Create(baseline).Error; err != nil {
+			return nil, err
+		}
+		latest = baseline.Revision
+	}
+
+	revision := models.NewProductRevision(product)
+	revision.Revision = latest + 1
+	if changedBy != 0 {
+		revision.ChangedBy = &changedBy
+	}
+	if err := tx.Omit("Product").Create(revision).Error; err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ProductChange is an update to apply to a product, with its state before the update
+type ProductChange struct {
+	Product  *models.Product
+	Previous *models.Product
+}
+
+// GetProductsBySKUs retrieves the active products with any of the given SKUs
+func (r *InventoryRepository) GetProductsBySKUs(skus []string) ([]models.Product, error) {
+	if len(skus) == 0 {
+		return nil, nil
+	}
+	return r.productRepo.List(context.Background(), WithWhere("sku IN ?", skus))
+}
+
+// ImportProducts creates and updates products in one transaction so a chunk of an
+// import is applied entirely or not at all. Updates write only the columns they
+// change and are stored as revisions like UpdateProductWithRevision.
+func (r *InventoryRepository) ImportProducts(creates []*models.Product, updates []ProductChange, changedBy uint) error {
+	ctx := context.Background()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(creates) > 0 {
+			if err := tx.Create(creates).Error; err != nil {
+				return err
+			}
+			adjustments := make([]*models.Transaction, 0, len(creates))
+			for _, product := range creates {
+				adjustments = append(adjustments, newAdjustment(product.ID, product.Quantity, adjustmentNotesInitial, changedBy))
+			}
+			if err := createAdjustments(tx, adjustments...); err != nil {
+				return err
+			}
+		}
+
+		return updateProductsWithRevisions(ctx, tx, updates, changedBy)
+	})
+}
+
+// updateProductsWithRevisions saves the columns each change touches on top of the
+// product's current state and stores it as the product's next revision
+func updateProductsWithRevisions(ctx context.Context, tx *gorm.DB, updates []ProductChange, changedBy uint) error {
+	products := NewBaseRepository[models.Product](tx)
+	for _, change := range updates {
+		if err := products.UpdateChanged(ctx, change.Product, change.Previous, 0, abcClassColumn); err != nil {
+			return err
+		}
+		if _, err := saveRevision(tx, change.Product, change.Previous, changedBy); err != nil {
+			return err
+		}
+		delta := change.Product.Quantity - change.Previous.Quantity
+		if err := createAdjustments(tx, newAdjustment(change.Product.ID, delta, adjustmentNotesEdited, changedBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProductsByIDs retrieves the active products with any of the given IDs in ID order
+func (r *InventoryRepository) GetProductsByIDs(ids []uint) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.productRepo.List(context.Background(), WithWhere("id IN ?", ids), WithOrder("id ASC"))
+}
+
+// GetProductsMatching retrieves up to limit products matching filter in ID order
+func (r *InventoryRepository) GetProductsMatching(filter *dtos.ProductFilter, limit int) ([]models.Product, error) {
+	scopes := r.buildProductFilterScopes(filter)
+	scopes = append(scopes, WithOrder("products.id ASC"), WithLimit(limit))
+	return r.productRepo.List(context.Background(), scopes...)
+}
+
+// BulkUpdateProducts applies all changes in one transaction, each written and
+// stored as a revision like UpdateProductWithRevision, so a bulk operation is
+// applied entirely or not at all
+func (r *InventoryRepository) BulkUpdateProducts(updates []ProductChange, changedBy uint) error {
+	ctx := context.Background()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return updateProductsWithRevisions(ctx, tx, updates, changedBy)
+	})
+}
+
+// BulkDeleteProducts soft-deletes the products in one statement. It fails with
+// ErrVersionConflict, deleting nothing, if any of them changed since they were loaded.
+func (r *InventoryRepository) BulkDeleteProducts(products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	keys := make([][]interface{}, len(products))
+	for i, product := range products {
+		keys[i] = []interface{}{product.ID, product.Version}
+	}
+
+	return r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("(id, version) IN ?", keys).Delete(&models.Product{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(products)) {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// GetProductRevisions retrieves a page of a product's revisions, newest first
+func (r *InventoryRepository) GetProductRevisions(productID uint, page dtos.PageRequest) ([]models.ProductRevision, dtos.PageInfo, error) {
+	return r.revisionRepo.ListPage(
+		context.Background(),
+		[]clause.OrderByColumn{{Column: clause.Column{Table: "product_revisions", Name: "revision"}, Desc: true}},
+		page.Cursor,
+		page.Limit,
+		WithWhere("product_id = ?", productID),
+		WithOffset(page.Offset),
+	)
+}
+
+func (r *InventoryRepository) CountProductRevisions(productID uint) (int64, error) {
+	return r.revisionRepo.Count(context.Background(), WithWhere("product_id = ?", productID))
+}
+
+func (r *InventoryRepository) GetProductRevision(productID, revision uint) (*models.ProductRevision, error) {
+	return r.revisionRepo.FindOne(context.Background(), WithWhere("product_id = ? AND revision = ?", productID, revision))
+}
+
+// Category operations
+func (r *InventoryRepository) CreateCategory(category *models.Category) error {
+	return r.categoryRepo.Create(context.Background(), category)
+}
+
+func (r *InventoryRepository) GetCategories() ([]models.Category, error) {
+	return r.categoryRepo.List(context.Background(), WithOrder("name ASC"))
+}
+
+func (r *InventoryRepository) GetCategoryByID(id uint) (*models.Category, error) {
+	return r.categoryRepo.GetByID(context.Background(), id)
+}
+
+func (r *InventoryRepository) GetCategoryByName(name string) (*models.Category, error) {
+	return r.categoryRepo.FindOne(context.Background(), WithWhere("LOWER(name) = LOWER(?)", name))
 }
 
 func (r *InventoryRepository) DeleteProduct(id uint) error {
 	return r.productRepo.Delete(context.Background(), id)
 }
 
+// GetProductByIDUnscoped retrieves a product even if it has been soft-deleted
+func (r *InventoryRepository) GetProductByIDUnscoped(id uint) (*models.Product, error) {
+	return r.productRepo.FindOne(context.Background(), WithUnscoped(), func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ?", id)
+	})
+}
+
+// Trash operations for soft-deleted products
+func (r *InventoryRepository) GetDeletedProducts(page dtos.PageRequest) ([]models.Product, dtos.PageInfo, error) {
+	return r.productRepo.ListPage(
+		context.Background(),
+		[]clause.OrderByColumn{
+			{Column: clause.Column{Table: "products", Name: "deleted_at"}, Desc: true},
+			{Column: clause.Column{Table: "products", Name: "id"}, Desc: true},
+		},
+		page.Cursor,
+		page.Limit,
+		WithUnscoped(),
+		WithWhere("deleted_at IS NOT NULL"),
+		WithOffset(page.Offset),
+	)
+}
+
+func (r *InventoryRepository) CountDeletedProducts() (int64, error) {
+	return r.productRepo.Count(context.Background(), WithUnscoped(), WithWhere("deleted_at IS NOT NULL"))
+}
+
+func (r *InventoryRepository) GetDeletedProductByID(id uint) (*models.Product, error) {
+	return r.productRepo.FindOne(
+		context.Background(),
+		WithUnscoped(),
+		WithWhere("id = ? AND deleted_at IS NOT NULL", id),
+	)
+}
+
+// RestoreProduct clears deleted_at so the product becomes visible again.
+// It returns gorm.ErrDuplicatedKey if an active product took the SKU meanwhile.
+func (r *InventoryRepository) RestoreProduct(id uint) error {
+	err := r.db.WithContext(context.Background()).
+		Unscoped().
+		Model(&models.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	if isUniqueViolation(err) {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+// CountMovementsByProductID counts the IN and OUT transactions of a product.
+// ADJUST rows are bookkeeping of quantity changes and do not count as history.
+func (r *InventoryRepository) CountMovementsByProductID(productID uint) (int64, error) {
+	return r.transactionRepo.Count(context.Background(),
+		WithWhere("product_id = ? AND transaction_type <> ?", productID, models.TransactionTypeAdjust))
+}
+
+// hasNoMovements matches products without IN or OUT transactions
+const hasNoMovements = "NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.product_id = products.id AND transactions.transaction_type <> ?)"
+
+// PurgeProduct permanently deletes a soft-deleted product along with its ADJUST
+// rows. It returns ErrProductHasMovements if the product has IN or OUT transactions.
+func (r *InventoryRepository) PurgeProduct(id uint) error {
+	return r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL").
+			First(&product, id).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("product_id = ? AND transaction_type = ?", id, models.TransactionTypeAdjust).
+			Delete(&models.Transaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Unscoped().
+			Where(hasNoMovements, models.TransactionTypeAdjust).
+			Delete(&product)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrProductHasMovements
+		}
+		return nil
+	})
+}
+
+// PurgeDeletedProducts permanently deletes all soft-deleted products that have no
+// IN or OUT transactions, along with their ADJUST rows, and returns the deleted products
+func (r *InventoryRepository) PurgeDeletedProducts() ([]models.Product, error) {
+	var purged []models.Product
+	err := r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("transaction_type = ?", models.TransactionTypeAdjust).
+			Where("product_id IN (SELECT products.id FROM products WHERE products.deleted_at IS NOT NULL AND "+hasNoMovements+")",
+				models.TransactionTypeAdjust).
+			Delete(&models.Transaction{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Clauses(clause.Returning{}).
+			Where("deleted_at IS NOT NULL").
+			Where(hasNoMovements, models.TransactionTypeAdjust).
+			Delete(&purged).Error
+	})
+	return purged, err
+}
+
 // Transaction operations using BaseRepository
 func (r *InventoryRepository) CreateTransaction(tx *models.Transaction) error {
 	return r.transactionRepo.Create(context.Background(), tx)
 }
 
-func (r *InventoryRepository) GetTransactionByID(id uint) (*models.Transaction, error) {
-	return r.transactionRepo.FindOne(
-		context.Background(),
+func (r *InventoryRepository) GetTransactionByID(id uint, projection dtos.Projection) (*models.Transaction, error) {
+	scopes := []func(*gorm.DB) *gorm.DB{
 		func(db *gorm.DB) *gorm.DB {
 			return db.Where("id = ?", id)
 		},
-		WithPreload("Product"),
-	)
+	}
+	scopes = append(scopes, transactionProjectionScopes(projection)...)
+
+	return r.transactionRepo.FindOne(context.Background(), scopes...)
 }
 
-func (r *InventoryRepository) GetTransactionsByProductID(productID uint, limit, offset int) ([]models.Transaction, error) {
-	return r.transactionRepo.List(
-		context.Background(),
+// GetTransactionsByProductID retrieves a page of the IN and OUT transactions of a product
+func (r *InventoryRepository) GetTransactionsByProductID(productID uint, page dtos.PageRequest, projection dtos.Projection) ([]models.Transaction, dtos.PageInfo, error) {
+	order := []clause.OrderByColumn{
+		{Column: clause.Column{Table: "transactions", Name: "created_at"}, Desc: true},
+		{Column: clause.Column{Table: "transactions", Name: "id"}, Desc: true},
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
 		func(db *gorm.DB) *gorm.DB {
 			return db.Where("product_id = ?", productID)
 		},
-		WithPreload("Product"),
-		WithLimit(limit),
-		WithOffset(offset),
-		WithOrder("created_at DESC"),
-	)
+		excludeAdjustments,
+		WithOffset(page.Offset),
+	}
+	scopes = append(scopes, transactionProjectionScopes(projection, orderColumnNames(order)...)...)
+
+	return r.transactionRepo.ListPage(context.Background(), order, page.Cursor, page.Limit, scopes...)
+}
+
+// GetTransactionsWithFilter retrieves a page of transactions with filtering and sorting support
+func (r *InventoryRepository) GetTransactionsWithFilter(filter *dtos.TransactionFilter, sort []dtos.SortField, page dtos.PageRequest, projection dtos.Projection) ([]models.Transaction, dtos.PageInfo, error) {
+	// Newest first unless another order is requested
+	if len(sort) == 0 {
+		sort = []dtos.SortField{{Field: "created_at", Desc: true}}
+	}
+	order := sortColumns("transactions", sort, clause.OrderByColumn{Column: clause.Column{Table: "transactions", Name: "id"}, Desc: true})
+
+	scopes := buildTransactionFilterScopes(filter)
+	scopes = append(scopes, WithOffset(page.Offset))
+	scopes = append(scopes, transactionProjectionScopes(projection, orderColumnNames(order)...)...)
+
+	return r.transactionRepo.ListPage(context.Background(), order, page.Cursor, page.Limit, scopes...)
+}
+
+// EachTransactionLedgerRow calls fn for every transaction matching filter oldest
+// first, with the product and recording user joined in. Rows are read from a
+// single snapshot through a database cursor.
+func (r *InventoryRepository) EachTransactionLedgerRow(filter *dtos.TransactionFilter, fn func(*models.TransactionLedgerRow) error) error {
+	scopes := buildTransactionFilterScopes(filter)
+	return streamSnapshot(r.db, func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.Transaction{}).
+			Select("transactions.*, products.sku AS product_sku, products.name AS product_name, users.username AS creator_name").
+			Joins("LEFT JOIN products ON products.id = transactions.product_id").
+			Joins("LEFT JOIN users ON users.id = transactions.created_by").
+			Scopes(scopes...).
+			Order("transactions.created_at ASC, transactions.id ASC")
+	}, fn)
+}
+
+// CountTransactionsWithFilter counts all transactions matching filter, ignoring pagination
+func (r *InventoryRepository) CountTransactionsWithFilter(filter *dtos.TransactionFilter) (int64, error) {
+	return r.transactionRepo.Count(context.Background(), buildTransactionFilterScopes(filter)...)
+}
+
+// buildTransactionFilterScopes converts TransactionFilter to GORM scopes. ADJUST
+// rows are left out unless the filter asks for them by type.
+func buildTransactionFilterScopes(filter *dtos.TransactionFilter) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{}
+
+	// Filter by transaction type
+	if filter.HasType() {
+		txType := *filter.Type
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.transaction_type = ?", txType)
+		})
+	} else {
+		scopes = append(scopes, excludeAdjustments)
+	}
+
+	if filter == nil || filter.IsEmpty() {
+		return scopes
+	}
+
+	// Filter by product
+	if filter.HasProductID() {
+		productID := *filter.ProductID
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.product_id = ?", productID)
+		})
+	}
+
+	// Filter by date range
+	if filter.HasFrom() {
+		from := *filter.From
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.created_at >= ?", from)
+		})
+	}
+	if filter.HasTo() {
+		to := *filter.To
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.created_at <= ?", to)
+		})
+	}
+
+	// Filter by acting user
+	if filter.HasUserID() {
+		userID := *filter.UserID
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.created_by = ?", userID)
+		})
+	}
+
+	// Search notes (partial match, case-insensitive)
+	if filter.HasNotes() {
+		notes := *filter.Notes
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("transactions.notes ILIKE ?", "%"+notes+"%")
+		})
+	}
+
+	return scopes
+}
+
+// excludeAdjustments leaves out ADJUST rows, which record quantity changes made
+// outside movements
+func excludeAdjustments(db *gorm.DB) *gorm.DB {
+	return db.Where("transactions.transaction_type <> ?", models.TransactionTypeAdjust)
 }
 
 func (r *InventoryRepository) GetAllTransactions(limit, offset int) ([]models.Transaction, error) {
 	return r.transactionRepo.List(
 		context.Background(),
-		WithPreload("Product"),
+		WithPreloadUnscoped("Product"),
 		WithLimit(limit),
 		WithOffset(offset),
 		WithOrder("created_at DESC"),
 	)
 }
 
-func (r *InventoryRepository) UpdateProductQuantityWithTransaction(productID uint, quantity int, txType models.TransactionType, notes string) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Get product
-		var product models.Product
-		if err := tx.First(&product, productID).Error; err != nil {
-			return err
+// Notes of the adjustments recorded for quantity changes outside movements
+const (
+	adjustmentNotesInitial = "Initial stock"
+	adjustmentNotesEdited  = "Quantity edited"
+)
+
+// newAdjustment builds the ADJUST ledger row for a quantity change made outside
+// IN/OUT movements, or nil when the quantity did not change
+func newAdjustment(productID uint, delta int, notes string, createdBy uint) *models.Transaction {
+	if delta == 0 {
+		return nil
+	}
+	adjustment := &models.Transaction{
+		ProductID:       productID,
+		Quantity:        delta,
+		TransactionType: models.TransactionTypeAdjust,
+		Notes:           notes,
+	}
+	if createdBy != 0 {
+		adjustment.CreatedBy = &createdBy
+	}
+	return adjustment
+}
+
+// createAdjustments writes the non-nil adjustments, so stock balances can be
+// rebuilt from the ledger alone
+func createAdjustments(tx *gorm.DB, adjustments ...*models.Transaction) error {
+	rows := make([]*models.Transaction, 0, len(adjustments))
+	for _, adjustment := range adjustments {
+		if adjustment != nil {
+			rows = append(rows, adjustment)
 		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Omit("Product", "Creator").Create(rows).Error
+}
 
-		// Update quantity
-		if txType == models.TransactionTypeIn {
-			product.Quantity += quantity
-		} else {
-			if product.Quantity < quantity {
-				return gorm.ErrInvalidData
+// UpdateProductQuantityWithTransaction adjusts stock and writes the ledger row atomically.
+// The product row is locked with SELECT ... FOR UPDATE so concurrent movements on the
+// same product are serialized and an OUT can never drive the quantity below zero.
+func (r *InventoryRepository) UpdateProductQuantityWithTransaction(productID uint, quantity int, txType models.TransactionType, notes string, createdBy uint) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := withRetry(func() error {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			// Get product and lock the row until commit
+			var product models.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+				return err
 			}
-			product.Quantity -= quantity
-		}
 
-		// Save product
-		if err := tx.Save(&product).Error; err != nil {
-			return err
-		}
+			if !models.AllowsMovement(product.Status, txType) {
+				return ErrMovementNotAllowed
+			}
+
+			// Update quantity
+			if txType == models.TransactionTypeIn {
+				product.Quantity += quantity
+			} else {
+				if product.Quantity < quantity {
+					return gorm.ErrInvalidData
+				}
+				product.Quantity -= quantity
+			}
+
+			// Only touch the quantity column so concurrent edits to other fields are kept
+			product.Version++
+			if err := tx.Model(&product).Updates(map[string]interface{}{
+				"quantity": product.Quantity,
+				"version":  product.Version,
+			}).Error; err != nil {
+				return err
+			}
+
+			// Create transaction record
+			transaction = &models.Transaction{
+				ProductID:       productID,
+				Product:         product,
+				Quantity:        quantity,
+				TransactionType: txType,
+				Notes:           notes,
+				CreatedBy:       &createdBy,
+			}
+			return tx.Omit("Product", "Creator").Create(transaction).Error
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
 
-		// Create transaction record
-		transaction := models.Transaction{
-			ProductID:       productID,
-			Quantity:        quantity,
-			TransactionType: txType,
-			Notes:           notes,
+// CreateTransactionBatch applies all movements in a single database transaction.
+// Products are locked in ascending ID order so concurrent batches touching the
+// same products cannot deadlock. If any line is invalid nothing is written and a
+// *dtos.BatchValidationError lists every rejected line.
+func (r *InventoryRepository) CreateTransactionBatch(transactions []models.Transaction) error {
+	productIDs := make([]uint, 0, len(transactions))
+	seen := make(map[uint]bool)
+	for _, t := range transactions {
+		if !seen[t.ProductID] {
+			seen[t.ProductID] = true
+			productIDs = append(productIDs, t.ProductID)
 		}
-		return tx.Create(&transaction).Error
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	return withRetry(func() error {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			// Lock all products up front in deterministic order
+			var products []models.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id IN ?", productIDs).
+				Order("id").
+				Find(&products).Error; err != nil {
+				return err
+			}
+
+			productsByID := make(map[uint]*models.Product, len(products))
+			for i := range products {
+				productsByID[products[i].ID] = &products[i]
+			}
+
+			// Apply lines in order against a running balance
+			var lineErrors []dtos.BatchLineError
+			for i, t := range transactions {
+				product, ok := productsByID[t.ProductID]
+				if !ok {
+					lineErrors = append(lineErrors, dtos.BatchLineError{
+						Line:    i,
+						Field:   "product_id",
+						Message: "product not found",
+						Value:   t.ProductID,
+					})
+					continue
+				}
+
+				if !models.AllowsMovement(product.Status, t.TransactionType) {
+					lineErrors = append(lineErrors, dtos.BatchLineError{
+						Line:    i,
+						Field:   "transaction_type",
+						Message: fmt.Sprintf("product is %s and does not accept %s movements", product.Status, t.TransactionType),
+						Value:   t.TransactionType,
+					})
+					continue
+				}
+
+				if t.TransactionType == models.TransactionTypeIn {
+					product.Quantity += t.Quantity
+				} else {
+					if product.Quantity < t.Quantity {
+						lineErrors = append(lineErrors, dtos.BatchLineError{
+							Line:    i,
+							Field:   "quantity",
+							Message: fmt.Sprintf("insufficient quantity for OUT transaction (available: %d)", product.Quantity),
+							Value:   t.Quantity,
+						})
+						continue
+					}
+					product.Quantity -= t.Quantity
+				}
+			}
+			if len(lineErrors) > 0 {
+				return &dtos.BatchValidationError{Errors: lineErrors}
+			}
+
+			// Write new quantities in the same order the locks were taken
+			for _, id := range productIDs {
+				product := productsByID[id]
+				product.Version++
+				if err := tx.Model(product).Updates(map[string]interface{}{
+					"quantity": product.Quantity,
+					"version":  product.Version,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			// Reset IDs in case a previous attempt was rolled back after insert
+			for i := range transactions {
+				transactions[i].ID = 0
+			}
+			if err := tx.Omit("Product", "Creator").Create(&transactions).Error; err != nil {
+				return err
+			}
+
+			for i := range transactions {
+				transactions[i].Product = *productsByID[transactions[i].ProductID]
+			}
+			return nil
+		})
 	})
 }
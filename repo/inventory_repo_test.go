@@ -0,0 +1,348 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"inventory-api/database/databasetest"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// createTestUser creates a user to record as the creator of movements, and
+// removes it when the test ends
+func createTestUser(t *testing.T, db *gorm.DB) uint {
+	t.Helper()
+	name := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	user := &models.User{Username: name, PasswordHashed: "-", Email: name + "@example.com", Role: "user", Phone: "-"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Delete(user)
+	})
+	return user.ID
+}
+
+// createTestProduct creates a product with an opening stock through the
+// repository, and removes it with its transactions when the test ends
+func createTestProduct(t *testing.T, db *gorm.DB, r *InventoryRepository, quantity int, createdBy uint) *models.Product {
+	t.Helper()
+	product := &models.Product{
+		Name:     t.Name(),
+		SKU:      fmt.Sprintf("TEST-%d", time.Now().UnixNano()),
+		Price:    10,
+		Quantity: quantity,
+		Status:   models.ProductStatusActive,
+	}
+	if err := r.CreateProduct(product, createdBy); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("product_id = ?", product.ID).Delete(&models.Transaction{})
+		db.Unscoped().Delete(&models.Product{}, product.ID)
+	})
+	return product
+}
+
+func countTransactions(t *testing.T, db *gorm.DB, productID uint) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.Transaction{}).Where("product_id = ?", productID).Count(&count).Error; err != nil {
+		t.Fatalf("count transactions: %v", err)
+	}
+	return count
+}
+
+func TestPurgeProductWithOpeningStock(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	product := createTestProduct(t, db, r, 5, userID)
+	if count := countTransactions(t, db, product.ID); count != 1 {
+		t.Fatalf("transactions after create = %d, want the initial stock adjustment", count)
+	}
+	if count, err := r.CountMovementsByProductID(product.ID); err != nil || count != 0 {
+		t.Fatalf("CountMovementsByProductID = %d, %v, want 0", count, err)
+	}
+
+	if err := r.DeleteProduct(product.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := r.PurgeProduct(product.ID); err != nil {
+		t.Fatalf("PurgeProduct: %v", err)
+	}
+	if count := countTransactions(t, db, product.ID); count != 0 {
+		t.Errorf("transactions after purge = %d, want 0", count)
+	}
+	if _, err := r.GetProductByIDUnscoped(product.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("product after purge: err = %v, want not found", err)
+	}
+}
+
+func TestPurgeProductWithMovements(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	product := createTestProduct(t, db, r, 5, userID)
+	if _, err := r.UpdateProductQuantityWithTransaction(product.ID, 2, models.TransactionTypeOut, "", userID); err != nil {
+		t.Fatalf("stock out: %v", err)
+	}
+	if err := r.DeleteProduct(product.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if err := r.PurgeProduct(product.ID); !errors.Is(err, ErrProductHasMovements) {
+		t.Fatalf("PurgeProduct err = %v, want ErrProductHasMovements", err)
+	}
+	// The refused purge must not have removed the adjustment either
+	if count := countTransactions(t, db, product.ID); count != 2 {
+		t.Errorf("transactions after refused purge = %d, want 2", count)
+	}
+}
+
+func TestPurgeDeletedProducts(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	unused := createTestProduct(t, db, r, 5, userID)
+	moved := createTestProduct(t, db, r, 5, userID)
+	if _, err := r.UpdateProductQuantityWithTransaction(moved.ID, 1, models.TransactionTypeIn, "", userID); err != nil {
+		t.Fatalf("stock in: %v", err)
+	}
+	for _, id := range []uint{unused.ID, moved.ID} {
+		if err := r.DeleteProduct(id); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+	}
+
+	purged, err := r.PurgeDeletedProducts()
+	if err != nil {
+		t.Fatalf("PurgeDeletedProducts: %v", err)
+	}
+	ids := make(map[uint]bool, len(purged))
+	for _, product := range purged {
+		ids[product.ID] = true
+	}
+	if !ids[unused.ID] {
+		t.Errorf("product with only its initial stock adjustment was not purged")
+	}
+	if ids[moved.ID] {
+		t.Errorf("product with movements was purged")
+	}
+	if count := countTransactions(t, db, unused.ID); count != 0 {
+		t.Errorf("transactions of purged product = %d, want 0", count)
+	}
+	if count := countTransactions(t, db, moved.ID); count != 2 {
+		t.Errorf("transactions of kept product = %d, want 2", count)
+	}
+}
+
+// TestConcurrentStockOut runs more OUT movements in parallel than the stock
+// covers. Row locking must serialize them so the quantity never drops below
+// zero and always matches the ledger.
+func TestConcurrentStockOut(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	const (
+		stock    = 50
+		workers  = 32
+		perOut   = 2
+		attempts = 3
+	)
+	product := createTestProduct(t, db, r, stock, userID)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		failures  []error
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range attempts {
+				transaction, err := r.UpdateProductQuantityWithTransaction(product.ID, perOut, models.TransactionTypeOut, "stress", userID)
+				mu.Lock()
+				switch {
+				case err == nil:
+					succeeded++
+					if transaction.Product.Quantity < 0 {
+						failures = append(failures, fmt.Errorf("quantity after OUT = %d", transaction.Product.Quantity))
+					}
+				case !errors.Is(err, gorm.ErrInvalidData):
+					failures = append(failures, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Error(err)
+	}
+	if want := stock / perOut; succeeded != want {
+		t.Errorf("successful OUT movements = %d, want %d", succeeded, want)
+	}
+
+	var quantity int
+	if err := db.Model(&models.Product{}).Where("id = ?", product.ID).Pluck("quantity", &quantity).Error; err != nil {
+		t.Fatalf("read quantity: %v", err)
+	}
+	var ledger int
+	err := db.Model(&models.Transaction{}).
+		Where("product_id = ?", product.ID).
+		Select("COALESCE(SUM(CASE WHEN transaction_type = ? THEN -quantity ELSE quantity END), 0)", models.TransactionTypeOut).
+		Scan(&ledger).Error
+	if err != nil {
+		t.Fatalf("sum ledger: %v", err)
+	}
+
+	if quantity < 0 {
+		t.Errorf("final quantity = %d, want >= 0", quantity)
+	}
+	if quantity != ledger {
+		t.Errorf("final quantity = %d, ledger sum = %d", quantity, ledger)
+	}
+	if want := stock - succeeded*perOut; quantity != want {
+		t.Errorf("final quantity = %d, want %d", quantity, want)
+	}
+}
+
+// TestUpdateProductAfterStockMovement edits a product loaded before a stock
+// movement. Without an expected version the edit must succeed and keep the
+// moved quantity; with the stale version it must be refused.
+func TestUpdateProductAfterStockMovement(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	product := createTestProduct(t, db, r, 5, userID)
+	t.Cleanup(func() {
+		db.Where("product_id = ?", product.ID).Delete(&models.ProductRevision{})
+	})
+	stale := *product
+	if _, err := r.UpdateProductQuantityWithTransaction(product.ID, 2, models.TransactionTypeOut, "", userID); err != nil {
+		t.Fatalf("stock out: %v", err)
+	}
+
+	edited, previous := stale, stale
+	edited.Price = 20
+	if _, err := r.UpdateProductWithRevision(&edited, &previous, stale.Version, userID); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("update with stale version: err = %v, want ErrVersionConflict", err)
+	}
+
+	edited, previous = stale, stale
+	edited.Price = 20
+	if _, err := r.UpdateProductWithRevision(&edited, &previous, 0, userID); err != nil {
+		t.Fatalf("update without version: %v", err)
+	}
+	if edited.Quantity != 3 || previous.Quantity != 3 {
+		t.Errorf("quantity after update = %d (previous %d), want 3", edited.Quantity, previous.Quantity)
+	}
+	if edited.Version != stale.Version+2 {
+		t.Errorf("version after update = %d, want %d", edited.Version, stale.Version+2)
+	}
+
+	saved, err := r.GetProductByID(product.ID)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if saved.Quantity != 3 || saved.Price != 20 {
+		t.Errorf("saved quantity, price = %d, %v, want 3, 20", saved.Quantity, saved.Price)
+	}
+	// Only the opening stock and the OUT movement, no adjustment for the edit
+	if count := countTransactions(t, db, product.ID); count != 2 {
+		t.Errorf("transactions = %d, want 2", count)
+	}
+}
+
+// TestUpdateProductKeepsABCClass edits a product loaded before an ABC analysis
+// stored its class. The edit must not write the stale class back.
+func TestUpdateProductKeepsABCClass(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	product := createTestProduct(t, db, r, 5, userID)
+	t.Cleanup(func() {
+		db.Where("product_id = ?", product.ID).Delete(&models.ProductRevision{})
+	})
+	stale := *product
+	if err := NewReportRepository(db).SetABCClasses(map[string][]uint{"A": {product.ID}}); err != nil {
+		t.Fatalf("SetABCClasses: %v", err)
+	}
+
+	for _, expectedVersion := range []uint{stale.Version, 0} {
+		edited, previous := stale, stale
+		edited.Price += 5
+		if _, err := r.UpdateProductWithRevision(&edited, &previous, expectedVersion, userID); err != nil {
+			t.Fatalf("update with expected version %d: %v", expectedVersion, err)
+		}
+
+		saved, err := r.GetProductByID(product.ID)
+		if err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+		if saved.ABCClass != "A" {
+			t.Errorf("class after update with expected version %d = %q, want A", expectedVersion, saved.ABCClass)
+		}
+	}
+}
+
+// TestTransactionListsLeaveOutAdjustments checks that ADJUST rows are only
+// listed when asked for by type
+func TestTransactionListsLeaveOutAdjustments(t *testing.T) {
+	db := databasetest.Open(t)
+	r := NewInventoryRepository(db)
+	userID := createTestUser(t, db)
+
+	product := createTestProduct(t, db, r, 5, userID)
+	if _, err := r.UpdateProductQuantityWithTransaction(product.ID, 2, models.TransactionTypeOut, "", userID); err != nil {
+		t.Fatalf("stock out: %v", err)
+	}
+
+	adjust := string(models.TransactionTypeAdjust)
+	tests := []struct {
+		name   string
+		filter *dtos.TransactionFilter
+		want   models.TransactionType
+	}{
+		{"default", &dtos.TransactionFilter{ProductID: &product.ID}, models.TransactionTypeOut},
+		{"type=ADJUST", &dtos.TransactionFilter{ProductID: &product.ID, Type: &adjust}, models.TransactionTypeAdjust},
+	}
+	page := dtos.PageRequest{Limit: 10}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, _, err := r.GetTransactionsWithFilter(tt.filter, nil, page, dtos.Projection{})
+			if err != nil {
+				t.Fatalf("GetTransactionsWithFilter: %v", err)
+			}
+			if len(transactions) != 1 || transactions[0].TransactionType != tt.want {
+				t.Errorf("transactions = %+v, want the %s row only", transactions, tt.want)
+			}
+			if count, err := r.CountTransactionsWithFilter(tt.filter); err != nil || count != 1 {
+				t.Errorf("CountTransactionsWithFilter = %d, %v, want 1", count, err)
+			}
+		})
+	}
+
+	history, _, err := r.GetTransactionsByProductID(product.ID, page, dtos.Projection{})
+	if err != nil {
+		t.Fatalf("GetTransactionsByProductID: %v", err)
+	}
+	if len(history) != 1 || history[0].TransactionType != models.TransactionTypeOut {
+		t.Errorf("product history = %+v, want the OUT row only", history)
+	}
+}
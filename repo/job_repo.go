@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrJobLeaseLost is returned when a worker updates a job it no longer holds,
+// because its lease ran out and another worker claimed the job
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+type JobRepository struct {
+	db      *gorm.DB
+	jobRepo *BaseRepository[models.Job]
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		db:      db,
+		jobRepo: NewBaseRepository[models.Job](db),
+	}
+}
+
+func (r *JobRepository) CreateJob(job *models.Job) error {
+	return r.jobRepo.Create(context.Background(), job)
+}
+
+// CreateScheduledJob creates job, which has no creator, unless another job of
+// the same type without a creator was created at or after since, reporting
+// whether it did. The advisory lock makes the check and insert atomic across replicas.
+func (r *JobRepository) CreateScheduledJob(job *models.Job, since time.Time) (bool, error) {
+	created := false
+	err := r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "job:"+job.Type).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&models.Job{}).
+			Where("type = ? AND created_by IS NULL AND created_at >= ?", job.Type, since).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *JobRepository) GetJobByID(id uint) (*models.Job, error) {
+	return r.jobRepo.GetByID(context.Background(), id)
+}
+
+// GetJobs retrieves a page of jobs, newest first
+func (r *JobRepository) GetJobs(filter *dtos.JobFilter, page dtos.PageRequest) ([]models.Job, dtos.PageInfo, error) {
+	scopes := buildJobFilterScopes(filter)
+	scopes = append(scopes, WithOffset(page.Offset))
+
+	return r.jobRepo.ListPage(
+		context.Background(),
+		[]clause.OrderByColumn{{Column: clause.Column{Table: "jobs", Name: "id"}, Desc: true}},
+		page.Cursor,
+		page.Limit,
+		scopes...,
+	)
+}
+
+func (r *JobRepository) CountJobs(filter *dtos.JobFilter) (int64, error) {
+	return r.jobRepo.Count(context.Background(), buildJobFilterScopes(filter)...)
+}
+
+// ClaimJob locks the next due job and leases it to workerID until the lease
+// expires. Due jobs are queued jobs whose run time has come and running jobs
+// whose lease ran out. SKIP LOCKED lets workers on several replicas claim jobs
+// concurrently without waiting on each other. It returns nil when no job is due.
+func (r *JobRepository) ClaimJob(workerID string, lease time.Duration) (*models.Job, error) {
+	var job models.Job
+	err := r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+				models.JobStatusQueued, now, models.JobStatusRunning, now).
+			Order("run_at ASC, id ASC").
+			Take(&job).Error
+		if err != nil {
+			return err
+		}
+
+		lockedUntil := now.Add(lease)
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.LockedBy = workerID
+		job.LockedUntil = &lockedUntil
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		return tx.Model(&job).Select("status", "attempts", "locked_by", "locked_until", "started_at", "updated_at").Updates(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RenewLease extends the lease of a running job held by workerID and reports
+// whether cancellation was requested
+func (r *JobRepository) RenewLease(id uint, workerID string, lease time.Duration) (bool, error) {
+	var job models.Job
+	result := r.db.WithContext(context.Background()).
+		Model(&job).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "cancel_requested"}}}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobStatusRunning, workerID).
+		Update("locked_until", time.Now().Add(lease))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrJobLeaseLost
+	}
+	return job.CancelRequested, nil
+}
+
+// UpdateJobProgress stores the completion percentage of a running job held by workerID
+func (r *JobRepository) UpdateJobProgress(id uint, workerID string, progress int) error {
+	result := r.db.WithContext(context.Background()).
+		Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobStatusRunning, workerID).
+		Update("progress", progress)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// ReleaseJob applies the outcome of a run to a job held by workerID and clears
+// the lease
+func (r *JobRepository) ReleaseJob(id uint, workerID string, updates map[string]interface{}) error {
+	updates["locked_by"] = ""
+	updates["locked_until"] = nil
+
+	result := r.db.WithContext(context.Background()).
+		Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobStatusRunning, workerID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// CancelJob cancels a queued job right away and asks the worker running a
+// running job to stop. It returns the job as it is afterwards.
+func (r *JobRepository) CancelJob(id uint) (*models.Job, error) {
+	db := r.db.WithContext(context.Background())
+	now := time.Now()
+
+	err := db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusQueued).
+		Updates(map[string]interface{}{"status": models.JobStatusCancelled, "finished_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusRunning).
+		Update("cancel_requested", true).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetJobByID(id)
+}
+
+// buildJobFilterScopes converts JobFilter to GORM scopes
+func buildJobFilterScopes(filter *dtos.JobFilter) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{}
+
+	if filter == nil {
+		return scopes
+	}
+
+	if filter.CreatedBy != nil {
+		scopes = append(scopes, WithWhere("created_by = ?", *filter.CreatedBy))
+	}
+	if filter.Status != nil {
+		scopes = append(scopes, WithWhere("status = ?", *filter.Status))
+	}
+	if filter.Type != nil {
+		scopes = append(scopes, WithWhere("type = ?", *filter.Type))
+	}
+
+	return scopes
+}
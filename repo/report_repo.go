@@ -0,0 +1,309 @@
+package repo
+
+import (
+	"context"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// GetUserMovements aggregates IN/OUT movements per acting user. Movements recorded
+// before the acting user was tracked are grouped under a nil user.
+func (r *ReportRepository) GetUserMovements(filter *dtos.TransactionFilter) ([]models.UserMovement, error) {
+	var results []models.UserMovement
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`transactions.created_by AS user_id,
+			users.username AS username,
+			COUNT(*) FILTER (WHERE transactions.transaction_type = ?) AS in_count,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS in_quantity,
+			COUNT(*) FILTER (WHERE transactions.transaction_type = ?) AS out_count,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS out_quantity,
+			MAX(transactions.created_at) AS last_moved_at`,
+			models.TransactionTypeIn, models.TransactionTypeIn, models.TransactionTypeOut, models.TransactionTypeOut).
+		Joins("LEFT JOIN users ON users.id = transactions.created_by").
+		Scopes(buildTransactionFilterScopes(filter)...).
+		Group("transactions.created_by, users.username").
+		Order("COUNT(*) DESC, transactions.created_by").
+		Scan(&results).Error
+	return results, err
+}
+
+// GetProductByIDUnscoped retrieves a product even if it has been soft-deleted
+func (r *ReportRepository) GetProductByIDUnscoped(id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(context.Background()).Unscoped().First(&product, id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// ledgerDelta is the signed stock change of a transaction row: OUT rows remove
+// their quantity, IN rows add it and ADJUST rows carry their own sign
+const ledgerDelta = "CASE WHEN transactions.transaction_type = ? THEN -transactions.quantity ELSE transactions.quantity END"
+
+// GetProductBalances rebuilds the stock of each product just before at from the
+// ledger, leaving out products with no stock
+func (r *ReportRepository) GetProductBalances(filter *dtos.MovementFilter, at time.Time) ([]models.ProductBalance, error) {
+	var results []models.ProductBalance
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select("transactions.product_id, products.sku, products.name, SUM("+ledgerDelta+") AS balance", models.TransactionTypeOut).
+		Joins("JOIN products ON products.id = transactions.product_id").
+		Scopes(buildMovementFilterScopes(filter)...).
+		Where("transactions.created_at < ?", at).
+		Group("transactions.product_id, products.sku, products.name").
+		Having("SUM("+ledgerDelta+") <> 0", models.TransactionTypeOut).
+		Scan(&results).Error
+	return results, err
+}
+
+// GetProductMovements totals IN, OUT and ADJUST rows per product and period in
+// the filter's range. Periods are truncated in UTC, weeks starting on Monday.
+func (r *ReportRepository) GetProductMovements(filter *dtos.MovementFilter) ([]models.ProductPeriodMovement, error) {
+	var results []models.ProductPeriodMovement
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`transactions.product_id, products.sku, products.name,
+			date_trunc(?, transactions.created_at AT TIME ZONE 'UTC') AS period,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS in_quantity,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS out_quantity,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS adjustments`,
+			filter.GroupBy, models.TransactionTypeIn, models.TransactionTypeOut, models.TransactionTypeAdjust).
+		Joins("JOIN products ON products.id = transactions.product_id").
+		Scopes(buildMovementFilterScopes(filter)...).
+		Where("transactions.created_at >= ? AND transactions.created_at < ?", filter.From, filter.To).
+		Group("transactions.product_id, products.sku, products.name, period").
+		Order("transactions.product_id, period").
+		Scan(&results).Error
+	return results, err
+}
+
+// buildMovementFilterScopes converts MovementFilter's product selection to GORM
+// scopes. Soft-deleted products are included, their history still counts.
+func buildMovementFilterScopes(filter *dtos.MovementFilter) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{}
+
+	if filter.ProductID != nil {
+		scopes = append(scopes, WithWhere("transactions.product_id = ?", *filter.ProductID))
+	}
+	if filter.CategoryID != nil {
+		scopes = append(scopes, WithWhere("products.category_id = ?", *filter.CategoryID))
+	}
+	return scopes
+}
+
+// GetConsumptionValues ranks the products by the value of their OUT movements in
+// [from, to), the quantity times the current price, highest first. Products that
+// did not move are included with a value of zero.
+func (r *ReportRepository) GetConsumptionValues(from, to time.Time) ([]models.ProductConsumption, error) {
+	var results []models.ProductConsumption
+	err := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(`products.id AS product_id, products.sku, products.name, products.price,
+			COALESCE(SUM(transactions.quantity), 0) AS out_quantity,
+			COALESCE(SUM(transactions.quantity), 0) * products.price AS consumption_value`).
+		Joins("LEFT JOIN transactions ON transactions.product_id = products.id AND transactions.transaction_type = ? AND transactions.created_at >= ? AND transactions.created_at < ?",
+			models.TransactionTypeOut, from, to).
+		Group("products.id").
+		Order("consumption_value DESC, products.id").
+		Scan(&results).Error
+	return results, err
+}
+
+// abcUpdateBatchSize limits the product IDs in one class update
+const abcUpdateBatchSize = 5000
+
+// SetABCClasses stores the class of every product given and clears it on all
+// others, in one transaction. The version is left alone: the class is derived
+// data and must not make concurrent edits fail their If-Match check.
+func (r *ReportRepository) SetABCClasses(classes map[string][]uint) error {
+	return r.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Product{}).
+			Where("abc_class <> ''").
+			UpdateColumn("abc_class", "").Error; err != nil {
+			return err
+		}
+		for class, ids := range classes {
+			for start := 0; start < len(ids); start += abcUpdateBatchSize {
+				batch := ids[start:min(start+abcUpdateBatchSize, len(ids))]
+				if err := tx.Model(&models.Product{}).
+					Where("id IN ?", batch).
+					UpdateColumn("abc_class", class).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetStockActivity lists the products with stock on hand and when they last
+// received and shipped stock
+func (r *ReportRepository) GetStockActivity(options *dtos.AgingOptions) ([]models.ProductStockActivity, error) {
+	var results []models.ProductStockActivity
+	err := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(`products.id AS product_id, products.sku, products.name, products.category_id,
+			products.price, products.quantity, products.created_at,
+			MAX(transactions.created_at) FILTER (WHERE transactions.transaction_type = ?) AS last_in_at,
+			MAX(transactions.created_at) FILTER (WHERE transactions.transaction_type = ?) AS last_out_at`,
+			models.TransactionTypeIn, models.TransactionTypeOut).
+		Joins("LEFT JOIN transactions ON transactions.product_id = products.id").
+		Scopes(buildStockFilterScopes(options)...).
+		Group("products.id").
+		Scan(&results).Error
+	return results, err
+}
+
+// GetStockLayers splits the on-hand quantity of each product with stock into the
+// receipts it came from, newest first, assuming stock leaves first in, first out.
+// Receipts are IN movements and positive adjustments; stock older than every
+// receipt is dated when the product was created.
+func (r *ReportRepository) GetStockLayers(options *dtos.AgingOptions) ([]models.StockLayer, error) {
+	receipts := r.db.
+		Table("transactions").
+		Select(`transactions.product_id, transactions.created_at, transactions.quantity,
+			SUM(transactions.quantity) OVER (PARTITION BY transactions.product_id ORDER BY transactions.created_at DESC, transactions.id DESC) AS received`).
+		Where("transactions.transaction_type = ? OR (transactions.transaction_type = ? AND transactions.quantity > 0)",
+			models.TransactionTypeIn, models.TransactionTypeAdjust)
+
+	var layers []models.StockLayer
+	err := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(`products.id AS product_id, receipts.created_at AS received_at,
+			LEAST(receipts.quantity, products.quantity - (receipts.received - receipts.quantity)) AS quantity`).
+		Joins("JOIN (?) AS receipts ON receipts.product_id = products.id", receipts).
+		Scopes(buildStockFilterScopes(options)...).
+		Where("receipts.received - receipts.quantity < products.quantity").
+		Scan(&layers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Stock the receipts do not cover, e.g. quantity set before the stock ledger existed
+	var uncovered []models.StockLayer
+	err = r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(`products.id AS product_id, products.created_at AS received_at,
+			products.quantity - COALESCE(SUM(receipts.quantity), 0) AS quantity`).
+		Joins("LEFT JOIN (?) AS receipts ON receipts.product_id = products.id AND receipts.received - receipts.quantity < products.quantity", receipts).
+		Scopes(buildStockFilterScopes(options)...).
+		Group("products.id").
+		Having("products.quantity > COALESCE(SUM(receipts.quantity), 0)").
+		Scan(&uncovered).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(layers, uncovered...), nil
+}
+
+// buildStockFilterScopes limits a stock report to the products with stock on
+// hand matching options
+func buildStockFilterScopes(options *dtos.AgingOptions) []func(*gorm.DB) *gorm.DB {
+	scopes := []func(*gorm.DB) *gorm.DB{
+		WithWhere("products.quantity > 0"),
+	}
+
+	if options.CategoryID != nil {
+		scopes = append(scopes, WithWhere("products.category_id = ?", *options.CategoryID))
+	}
+	if options.ABCClass != nil {
+		scopes = append(scopes, WithWhere("products.abc_class = ?", *options.ABCClass))
+	}
+	return scopes
+}
+
+// GetStockSummary totals the stock of all products in one pass. Stockouts and low
+// stock only count active products.
+func (r *ReportRepository) GetStockSummary(lowStockThreshold int) (*models.StockSummary, error) {
+	var summary models.StockSummary
+	err := r.db.WithContext(context.Background()).
+		Model(&models.Product{}).
+		Select(`COUNT(*) AS product_count,
+			COALESCE(SUM(products.quantity), 0) AS total_quantity,
+			COALESCE(SUM(products.quantity * products.price), 0) AS total_value,
+			COUNT(*) FILTER (WHERE products.status = ? AND products.quantity <= 0) AS stockout_count,
+			COUNT(*) FILTER (WHERE products.status = ? AND products.quantity > 0 AND products.quantity <= ?) AS low_stock_count`,
+			models.ProductStatusActive, models.ProductStatusActive, lowStockThreshold).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// GetMovementValueTotals totals the OUT movements and the net stock change since
+// since, valued at the current prices of products that are not deleted
+func (r *ReportRepository) GetMovementValueTotals(since time.Time) (*models.MovementValueTotals, error) {
+	var totals models.MovementValueTotals
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS out_quantity,
+			COALESCE(SUM(transactions.quantity * products.price) FILTER (WHERE transactions.transaction_type = ?), 0) AS out_value,
+			COALESCE(SUM(`+ledgerDelta+` * products.price), 0) AS net_value`,
+			models.TransactionTypeOut, models.TransactionTypeOut, models.TransactionTypeOut).
+		Joins("JOIN products ON products.id = transactions.product_id AND products.deleted_at IS NULL").
+		Where("transactions.created_at >= ?", since).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// GetTopMovers lists the limit products with the most stock moved out since
+// since, optionally only counting the movements recorded by userID
+func (r *ReportRepository) GetTopMovers(since time.Time, userID *uint, limit int) ([]models.ProductMover, error) {
+	var results []models.ProductMover
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`transactions.product_id, products.sku, products.name,
+			SUM(transactions.quantity) AS out_quantity,
+			SUM(transactions.quantity * products.price) AS out_value`).
+		Joins("JOIN products ON products.id = transactions.product_id").
+		Scopes(buildCreatedByScopes(userID)...).
+		Where("transactions.transaction_type = ? AND transactions.created_at >= ?", models.TransactionTypeOut, since).
+		Group("transactions.product_id, products.sku, products.name").
+		Order("out_quantity DESC, transactions.product_id").
+		Limit(limit).
+		Scan(&results).Error
+	return results, err
+}
+
+// GetDailyMovements totals IN and OUT quantities per UTC day since since,
+// optionally only counting the movements recorded by userID. Days without
+// movements are left out.
+func (r *ReportRepository) GetDailyMovements(since time.Time, userID *uint) ([]models.DailyMovement, error) {
+	var results []models.DailyMovement
+	err := r.db.WithContext(context.Background()).
+		Table("transactions").
+		Select(`date_trunc('day', transactions.created_at AT TIME ZONE 'UTC') AS day,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS in_quantity,
+			COALESCE(SUM(transactions.quantity) FILTER (WHERE transactions.transaction_type = ?), 0) AS out_quantity`,
+			models.TransactionTypeIn, models.TransactionTypeOut).
+		Scopes(buildCreatedByScopes(userID)...).
+		Where("transactions.transaction_type IN ? AND transactions.created_at >= ?",
+			[]models.TransactionType{models.TransactionTypeIn, models.TransactionTypeOut}, since).
+		Group("day").
+		Order("day").
+		Scan(&results).Error
+	return results, err
+}
+
+func buildCreatedByScopes(userID *uint) []func(*gorm.DB) *gorm.DB {
+	if userID == nil {
+		return nil
+	}
+	return []func(*gorm.DB) *gorm.DB{WithWhere("transactions.created_by = ?", *userID)}
+}
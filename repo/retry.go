@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes that indicate a transaction may succeed if retried
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgUniqueViolation      = "23505"
+)
+
+const (
+	maxTxRetries   = 5
+	txRetryBackoff = 10 * time.Millisecond
+)
+
+// withRetry runs fn again when it fails with a serialization failure or deadlock
+func withRetry(fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxTxRetries; attempt++ {
+		err = fn()
+		if err == nil || !isRetryableError(err) {
+			return err
+		}
+		time.Sleep(txRetryBackoff * time.Duration(attempt+1))
+	}
+	return err
+}
+
+// isRetryableError checks if err is a transient Postgres concurrency error
+func isRetryableError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+	}
+	return false
+}
+
+// isUniqueViolation checks if err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
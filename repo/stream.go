@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// streamFetchSize is how many rows are fetched from a server-side cursor at a time
+const streamFetchSize = 1000
+
+// streamSnapshot runs query through a server-side cursor and calls fn for every
+// row without loading the result into memory. The cursor lives in a read-only
+// repeatable-read transaction, so all rows come from one consistent snapshot
+// even while other requests keep writing.
+func streamSnapshot[T any](db *gorm.DB, query func(*gorm.DB) *gorm.DB, fn func(*T) error) error {
+	ctx := context.Background()
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Build the SELECT without running it, then declare the cursor over it
+		stmt := query(tx.Session(&gorm.Session{DryRun: true})).Find(&[]T{}).Statement
+		if stmt.Error != nil {
+			return stmt.Error
+		}
+		declare := "DECLARE stream_cursor NO SCROLL CURSOR FOR " + stmt.SQL.String()
+		if _, err := tx.Statement.ConnPool.ExecContext(ctx, declare, stmt.Vars...); err != nil {
+			return err
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM stream_cursor", streamFetchSize)
+		for {
+			var rows []T
+			if err := tx.Raw(fetch).Scan(&rows).Error; err != nil {
+				return err
+			}
+			for i := range rows {
+				if err := fn(&rows[i]); err != nil {
+					return err
+				}
+			}
+			if len(rows) < streamFetchSize {
+				return nil
+			}
+		}
+	}, opts)
+}
@@ -2,9 +2,11 @@ package repo
 
 import (
 	"context"
+	"inventory-api/dtos"
 	"inventory-api/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -19,6 +21,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *UserRepository) WithTx(tx *gorm.DB) UserRepositoryInterface {
+	return NewUserRepository(tx)
+}
+
 func (r *UserRepository) GetUserByID(id uint) (*models.User, error) {
 	return r.userRepo.GetByID(context.Background(), id)
 }
@@ -29,14 +36,20 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 	})
 }
 
-func (r *UserRepository) GetAllUsers(limit, offset int) ([]models.User, error) {
-	return r.userRepo.List(
+func (r *UserRepository) GetAllUsers(page dtos.PageRequest) ([]models.User, dtos.PageInfo, error) {
+	return r.userRepo.ListPage(
 		context.Background(),
-		WithLimit(limit),
-		WithOffset(offset),
+		[]clause.OrderByColumn{{Column: clause.Column{Table: "users", Name: "id"}}},
+		page.Cursor,
+		page.Limit,
+		WithOffset(page.Offset),
 	)
 }
 
+func (r *UserRepository) CountUsers() (int64, error) {
+	return r.userRepo.Count(context.Background())
+}
+
 func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return r.userRepo.FindOne(context.Background(), func(db *gorm.DB) *gorm.DB {
 		return db.Where("email = ?", email)
@@ -57,9 +70,11 @@ func (r *UserRepository) CreateUser(user *models.User) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) UpdateUser(user *models.User) (*models.User, error) {
-	err := r.userRepo.Update(context.Background(), user)
-	if err != nil {
+// UpdateUser saves the columns of user that differ from previous and bumps its
+// version. With a non-zero expectedVersion it fails with ErrVersionConflict if the
+// user is no longer at that version.
+func (r *UserRepository) UpdateUser(user, previous *models.User, expectedVersion uint) (*models.User, error) {
+	if err := r.userRepo.UpdateChanged(context.Background(), user, previous, expectedVersion); err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -1,6 +1,11 @@
 package repo
 
-import "inventory-api/models"
+import (
+	"inventory-api/dtos"
+	"inventory-api/models"
+
+	"gorm.io/gorm"
+)
 
 // UserRepositoryInterface định nghĩa contract cho UserRepository
 // Giúp dễ dàng mock trong tests
@@ -9,10 +14,12 @@ type UserRepositoryInterface interface {
 	GetUserByUsername(username string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByPhone(phone string) (*models.User, error)
-	GetAllUsers(limit, offset int) ([]models.User, error)
+	GetAllUsers(page dtos.PageRequest) ([]models.User, dtos.PageInfo, error)
+	CountUsers() (int64, error)
 	CreateUser(user *models.User) (*models.User, error)
-	UpdateUser(user *models.User) (*models.User, error)
+	UpdateUser(user, previous *models.User, expectedVersion uint) (*models.User, error)
 	DeleteUser(id uint) error
+	WithTx(tx *gorm.DB) UserRepositoryInterface
 }
 
 // Verify UserRepository implements the interface
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"inventory-api/storage"
+	"inventory-api/utils"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFileTooLarge        = errors.New("file exceeds the maximum allowed size")
+	ErrUnsupportedFileType = errors.New("file type is not allowed")
+	ErrImageTooLarge       = errors.New("image dimensions exceed the maximum allowed")
+)
+
+// thumbnailSize is the longest side of generated thumbnails in pixels
+const thumbnailSize = 256
+
+// Content types accepted for uploads, detected from the file content
+var (
+	allowedImageTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+	}
+	allowedAttachmentTypes = map[string]string{
+		"application/pdf": ".pdf",
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"image/gif":       ".gif",
+		"text/plain":      ".txt",
+	}
+)
+
+// FileDownload is an opened stored file ready to be streamed to the client
+type FileDownload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.ReadCloser
+}
+
+type AttachmentService struct {
+	repo              *repo.AttachmentRepository
+	inventoryRepo     *repo.InventoryRepository
+	storage           storage.Storage
+	maxImageSize      int64
+	maxAttachmentSize int64
+	audit             *AuditService
+}
+
+func NewAttachmentService(repo *repo.AttachmentRepository, inventoryRepo *repo.InventoryRepository, storage storage.Storage, maxImageSize, maxAttachmentSize int64, audit *AuditService) *AttachmentService {
+	return &AttachmentService{
+		repo:              repo,
+		inventoryRepo:     inventoryRepo,
+		storage:           storage,
+		maxImageSize:      maxImageSize,
+		maxAttachmentSize: maxAttachmentSize,
+		audit:             audit,
+	}
+}
+
+func (s *AttachmentService) MaxImageSize() int64 {
+	return s.maxImageSize
+}
+
+func (s *AttachmentService) MaxAttachmentSize() int64 {
+	return s.maxAttachmentSize
+}
+
+// Product image services
+func (s *AttachmentService) UploadProductImage(productID uint, fileName string, file io.Reader, size int64, actor Actor) (*dtos.ProductImageResponse, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	if size > s.maxImageSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// Images are small enough to buffer, and the thumbnail needs the whole file anyway
+	data, err := io.ReadAll(io.LimitReader(file, s.maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxImageSize {
+		return nil, ErrFileTooLarge
+	}
+
+	contentType := detectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedFileType
+	}
+
+	thumbnail, thumbnailType, err := utils.GenerateThumbnail(data, thumbnailSize)
+	if err != nil {
+		if errors.Is(err, utils.ErrImageTooLarge) {
+			return nil, ErrImageTooLarge
+		}
+		return nil, errors.New("invalid image file")
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("products/%d/images/%s%s", productID, randomName(), ext)
+	thumbnailKey := "thumbnails/" + key
+	if thumbnailType == "image/png" {
+		thumbnailKey = strings.TrimSuffix(thumbnailKey, ext) + ".png"
+	}
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	if err := s.storage.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType); err != nil {
+		s.removeObjects(key)
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	image := &models.ProductImage{
+		ProductID:    productID,
+		FileName:     sanitizeFileName(fileName, ext),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		StorageKey:   key,
+		ThumbnailKey: thumbnailKey,
+		UploadedBy:   actor.UserID,
+	}
+	var response *dtos.ProductImageResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).CreateProductImage(image); err != nil {
+			return err
+		}
+		response = dtos.ToProductImageResponse(image)
+		audit.Record(AuditEntityProductImage, image.ID, AuditActionCreate, nil, response)
+		return nil
+	})
+	if err != nil {
+		s.removeObjects(key, thumbnailKey)
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *AttachmentService) GetProductImages(productID uint) ([]dtos.ProductImageResponse, error) {
+	if _, err := s.inventoryRepo.GetProductByID(productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	images, err := s.repo.GetProductImages(productID)
+	if err != nil {
+		return nil, err
+	}
+	return dtos.ToProductImageResponseList(images), nil
+}
+
+// OpenProductImage opens the original image, or its thumbnail when thumbnail is true
+func (s *AttachmentService) OpenProductImage(productID, imageID uint, thumbnail bool) (*FileDownload, error) {
+	image, err := s.repo.GetProductImage(productID, imageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("image not found")
+		}
+		return nil, err
+	}
+
+	key := image.StorageKey
+	download := &FileDownload{
+		FileName:    image.FileName,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+	}
+	if thumbnail {
+		key = image.ThumbnailKey
+		download.ContentType = thumbnailContentType(key)
+		download.Size = 0
+	}
+
+	content, err := s.storage.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errors.New("image file not found")
+		}
+		return nil, err
+	}
+	download.Content = content
+	return download, nil
+}
+
+func (s *AttachmentService) DeleteProductImage(productID, imageID uint, actor Actor) error {
+	image, err := s.repo.GetProductImage(productID, imageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("image not found")
+		}
+		return err
+	}
+
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).DeleteProductImage(image.ID); err != nil {
+			return err
+		}
+		audit.Record(AuditEntityProductImage, image.ID, AuditActionDelete, dtos.ToProductImageResponse(image), nil)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files are only removed once the row is gone, so a rollback leaves them in place
+	s.removeObjects(image.StorageKey, image.ThumbnailKey)
+	return nil
+}
+
+// Transaction attachment services
+func (s *AttachmentService) UploadTransactionAttachment(transactionID uint, fileName string, file io.Reader, size int64, actor Actor) (*dtos.AttachmentResponse, error) {
+	if _, err := s.inventoryRepo.GetTransactionByID(transactionID, dtos.Projection{}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transaction not found")
+		}
+		return nil, err
+	}
+
+	if size > s.maxAttachmentSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// Sniff the content type from the first bytes, then stream the rest
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := detectContentType(head)
+	ext, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedFileType
+	}
+
+	key := fmt.Sprintf("transactions/%d/attachments/%s%s", transactionID, randomName(), ext)
+	content := io.MultiReader(bytes.NewReader(head), file)
+	if err := s.storage.Put(context.Background(), key, content, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	attachment := &models.TransactionAttachment{
+		TransactionID: transactionID,
+		FileName:      sanitizeFileName(fileName, ext),
+		ContentType:   contentType,
+		Size:          size,
+		StorageKey:    key,
+		UploadedBy:    actor.UserID,
+	}
+	var response *dtos.AttachmentResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).CreateTransactionAttachment(attachment); err != nil {
+			return err
+		}
+		response = dtos.ToAttachmentResponse(attachment)
+		audit.Record(AuditEntityTransactionAttachment, attachment.ID, AuditActionCreate, nil, response)
+		return nil
+	})
+	if err != nil {
+		s.removeObjects(key)
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *AttachmentService) GetTransactionAttachments(transactionID uint) ([]dtos.AttachmentResponse, error) {
+	if _, err := s.inventoryRepo.GetTransactionByID(transactionID, dtos.Projection{}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transaction not found")
+		}
+		return nil, err
+	}
+
+	attachments, err := s.repo.GetTransactionAttachments(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return dtos.ToAttachmentResponseList(attachments), nil
+}
+
+func (s *AttachmentService) OpenTransactionAttachment(transactionID, attachmentID uint) (*FileDownload, error) {
+	attachment, err := s.repo.GetTransactionAttachment(transactionID, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+
+	content, err := s.storage.Get(context.Background(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errors.New("attachment file not found")
+		}
+		return nil, err
+	}
+
+	return &FileDownload{
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Content:     content,
+	}, nil
+}
+
+// removeObjects deletes stored files on a best-effort basis
+func (s *AttachmentService) removeObjects(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+// detectContentType sniffs the media type and drops parameters such as charset
+func detectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+func thumbnailContentType(key string) string {
+	if strings.HasSuffix(key, ".png") {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// sanitizeFileName keeps only the base name of a client-supplied file name
+func sanitizeFileName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file" + ext
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+func randomName() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
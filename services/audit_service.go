@@ -0,0 +1,325 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audited entity types
+const (
+	AuditEntityProduct               = "product"
+	AuditEntityProductImage          = "product_image"
+	AuditEntityTransaction           = "transaction"
+	AuditEntityTransactionAttachment = "transaction_attachment"
+	AuditEntityUser                  = "user"
+	AuditEntityCategory              = "category"
+)
+
+// Audited actions
+const (
+	AuditActionCreate         = "create"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionRestore        = "restore"
+	AuditActionPurge          = "purge"
+	AuditActionStatusChange   = "status_change"
+	AuditActionPasswordChange = "password_change"
+	AuditActionBulkUpdate     = "bulk_update"
+	AuditActionBulkDelete     = "bulk_delete"
+)
+
+// ErrUnsupportedExportFormat is returned for export formats other than csv and ndjson
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// Actor identifies who performed an operation and from where. A zero UserID
+// means an anonymous caller, e.g. self-registration.
+type Actor struct {
+	UserID    uint
+	Username  string
+	RequestID string
+	IP        string
+}
+
+// fieldChange is one entry of an audit diff
+type fieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+type AuditService struct {
+	repo *repo.AuditRepository
+}
+
+func NewAuditService(repo *repo.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// AuditChange is one changed entity for RecordAll
+type AuditChange struct {
+	EntityID uint
+	Before   interface{}
+	After    interface{}
+}
+
+// AuditRecorder collects the audit entries of a change made in a Transaction
+type AuditRecorder struct {
+	actor   Actor
+	entries []*models.AuditLog
+	err     error
+}
+
+// Record adds an entry describing a change. before and after are snapshots of
+// the entity (nil for creates and deletes respectively).
+func (r *AuditRecorder) Record(entity string, entityID uint, action string, before, after interface{}) {
+	r.RecordAll(entity, action, []AuditChange{{EntityID: entityID, Before: before, After: after}})
+}
+
+// RecordAll adds one entry per change, e.g. for batch operations
+func (r *AuditRecorder) RecordAll(entity, action string, changes []AuditChange) {
+	for _, change := range changes {
+		if r.err != nil {
+			return
+		}
+		entry, err := newAuditEntry(r.actor, entity, change.EntityID, action, change.Before, change.After)
+		if err != nil {
+			r.err = fmt.Errorf("audit: failed to encode %s %s %d: %w", action, entity, change.EntityID, err)
+			return
+		}
+		r.entries = append(r.entries, entry)
+	}
+}
+
+// Transaction runs fn in a database transaction and appends the entries fn
+// records before committing, so a change is never committed without its audit
+// entries. Repositories fn writes through must be bound to tx with WithTx. If the
+// entries cannot be written the change is rolled back and the error returned.
+func (s *AuditService) Transaction(actor Actor, fn func(tx *gorm.DB, audit *AuditRecorder) error) error {
+	return s.repo.Transaction(func(tx *gorm.DB) error {
+		recorder := &AuditRecorder{actor: actor}
+		if err := fn(tx, recorder); err != nil {
+			return err
+		}
+		if recorder.err != nil {
+			return recorder.err
+		}
+		if len(recorder.entries) == 0 {
+			return nil
+		}
+		// Appended last, the chain lock is only held while committing
+		if err := s.repo.WithTx(tx).Append(recorder.entries...); err != nil {
+			return fmt.Errorf("audit: failed to record entries: %w", err)
+		}
+		return nil
+	})
+}
+
+func newAuditEntry(actor Actor, entity string, entityID uint, action string, before, after interface{}) (*models.AuditLog, error) {
+	entry := &models.AuditLog{
+		ActorName: actor.Username,
+		Entity:    entity,
+		Action:    action,
+		RequestID: actor.RequestID,
+		IP:        actor.IP,
+		// Postgres keeps microseconds, truncate so the stored row hashes the same
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if actor.UserID != 0 {
+		entry.ActorID = &actor.UserID
+	}
+	if entityID != 0 {
+		entry.EntityID = &entityID
+	}
+
+	beforeJSON, err := snapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := snapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = beforeJSON
+	entry.After = afterJSON
+	if beforeJSON != nil && afterJSON != nil {
+		entry.Changes = diffSnapshots(*beforeJSON, *afterJSON)
+	}
+	return entry, nil
+}
+
+// GetAuditLogs retrieves a page of audit entries, newest first
+func (s *AuditService) GetAuditLogs(filter *dtos.AuditFilter, page dtos.PageRequest) ([]dtos.AuditLogResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	entries, info, err := s.repo.GetAuditLogs(filter, page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountAuditLogs(filter) }); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	return dtos.ToAuditLogResponseList(entries), info, nil
+}
+
+// Export streams matching entries to w as CSV or newline-delimited JSON
+func (s *AuditService) Export(filter *dtos.AuditFilter, format string, w io.Writer) error {
+	switch format {
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		return s.repo.EachAuditLog(filter, func(entry *models.AuditLog) error {
+			return encoder.Encode(dtos.ToAuditLogResponse(entry))
+		})
+	case "csv":
+		writer := csv.NewWriter(w)
+		header := []string{"id", "created_at", "actor_id", "actor_name", "entity", "entity_id", "action", "changes", "before", "after", "request_id", "ip", "prev_hash", "hash"}
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+		err := s.repo.EachAuditLog(filter, func(entry *models.AuditLog) error {
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(entry.ID), 10),
+				entry.CreatedAt.Format(time.RFC3339),
+				optionalID(entry.ActorID),
+				entry.ActorName,
+				entry.Entity,
+				optionalID(entry.EntityID),
+				entry.Action,
+				optionalString(entry.Changes),
+				optionalString(entry.Before),
+				optionalString(entry.After),
+				entry.RequestID,
+				entry.IP,
+				entry.PrevHash,
+				entry.Hash,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return ErrUnsupportedExportFormat
+}
+
+// Verify walks the whole chain and reports the first entry whose hash or link
+// to its predecessor does not match
+func (s *AuditService) Verify() (*dtos.AuditVerifyResponse, error) {
+	result := &dtos.AuditVerifyResponse{Valid: true}
+	prevHash := ""
+
+	err := s.repo.EachAuditLog(nil, func(entry *models.AuditLog) error {
+		result.Checked++
+		if !result.Valid {
+			return nil
+		}
+
+		switch {
+		case entry.PrevHash != prevHash:
+			result.Message = fmt.Sprintf("entry %d does not link to the previous entry", entry.ID)
+		case entry.ComputeHash() != entry.Hash:
+			result.Message = fmt.Sprintf("entry %d content does not match its hash", entry.ID)
+		default:
+			prevHash = entry.Hash
+			return nil
+		}
+
+		id := entry.ID
+		result.Valid = false
+		result.FirstInvalidID = &id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// snapshot encodes an entity snapshot, nil stays nil
+func snapshot(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(data)
+	return &encoded, nil
+}
+
+// diffSnapshots returns a JSON object of the top-level fields that differ
+// between two snapshots, or nil if they cannot be compared
+func diffSnapshots(before, after string) *string {
+	changes, err := diffFields([]byte(before), []byte(after))
+	if err != nil {
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil
+	}
+	encoded := string(data)
+	return &encoded
+}
+
+// diffFields compares two JSON objects and returns the top-level fields that differ
+func diffFields(before, after []byte) (map[string]fieldChange, error) {
+	var beforeFields, afterFields map[string]json.RawMessage
+	if err := json.Unmarshal(before, &beforeFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]fieldChange)
+	compare := func(key string) {
+		from, to := beforeFields[key], afterFields[key]
+		if string(from) != string(to) {
+			changes[key] = fieldChange{From: nullIfEmpty(from), To: nullIfEmpty(to)}
+		}
+	}
+	for key := range beforeFields {
+		compare(key)
+	}
+	for key := range afterFields {
+		compare(key)
+	}
+	return changes, nil
+}
+
+func nullIfEmpty(value json.RawMessage) json.RawMessage {
+	if len(value) == 0 {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,37 @@
+package services
+
+import "testing"
+
+func TestAuditRecorder(t *testing.T) {
+	recorder := &AuditRecorder{actor: Actor{UserID: 3, Username: "alice", RequestID: "req-1"}}
+	recorder.Record(AuditEntityProduct, 7, AuditActionUpdate, map[string]int{"quantity": 1}, map[string]int{"quantity": 2})
+	recorder.RecordAll(AuditEntityProduct, AuditActionPurge, []AuditChange{{EntityID: 8}, {EntityID: 9}})
+
+	if recorder.err != nil {
+		t.Fatalf("err = %v", recorder.err)
+	}
+	if len(recorder.entries) != 3 {
+		t.Fatalf("entries = %d, want 3", len(recorder.entries))
+	}
+	entry := recorder.entries[0]
+	if *entry.ActorID != 3 || entry.RequestID != "req-1" || *entry.EntityID != 7 {
+		t.Errorf("entry = %+v, want actor 3, request req-1, entity 7", entry)
+	}
+	if entry.Changes == nil || *entry.Changes != `{"quantity":{"from":1,"to":2}}` {
+		t.Errorf("changes = %v", entry.Changes)
+	}
+}
+
+// An entry that cannot be encoded must fail the transaction rather than be dropped
+func TestAuditRecorderEncodeError(t *testing.T) {
+	recorder := &AuditRecorder{}
+	recorder.Record(AuditEntityProduct, 1, AuditActionCreate, nil, make(chan int))
+	recorder.Record(AuditEntityProduct, 2, AuditActionCreate, nil, nil)
+
+	if recorder.err == nil {
+		t.Fatal("err = nil, want an encoding error")
+	}
+	if len(recorder.entries) != 0 {
+		t.Errorf("entries = %d, want none after the error", len(recorder.entries))
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/repo"
+	"sync"
+	"time"
+)
+
+// DashboardService computes the inventory KPIs and keeps them for a while, so
+// frequently refreshed dashboards do not run the aggregates on every request
+type DashboardService struct {
+	repo              *repo.ReportRepository
+	lowStockThreshold int
+	cacheTTL          time.Duration
+
+	mu    sync.Mutex
+	cache map[string]dashboardCacheEntry
+}
+
+type dashboardCacheEntry struct {
+	dashboard *dtos.Dashboard
+	expiresAt time.Time
+}
+
+// NewDashboardService creates a dashboard service. A cacheTTL of zero disables caching.
+func NewDashboardService(repo *repo.ReportRepository, lowStockThreshold int, cacheTTL time.Duration) *DashboardService {
+	return &DashboardService{
+		repo:              repo,
+		lowStockThreshold: lowStockThreshold,
+		cacheTTL:          cacheTTL,
+		cache:             make(map[string]dashboardCacheEntry),
+	}
+}
+
+// CacheTTL is how long a computed dashboard is served from cache
+func (s *DashboardService) CacheTTL() time.Duration {
+	return s.cacheTTL
+}
+
+// GetDashboard returns the KPIs for options, from cache when computed less than
+// the cache interval ago. Each scope is cached separately, so a user never sees
+// figures computed for someone else.
+func (s *DashboardService) GetDashboard(options *dtos.DashboardOptions) (*dtos.Dashboard, error) {
+	key := fmt.Sprintf("%s:%d:%d", dashboardScopeKey(options), options.Days, options.Top)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.dashboard, nil
+	}
+
+	dashboard, err := s.computeDashboard(options, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cacheTTL > 0 {
+		s.mu.Lock()
+		for k, e := range s.cache {
+			if !now.Before(e.expiresAt) {
+				delete(s.cache, k)
+			}
+		}
+		s.cache[key] = dashboardCacheEntry{dashboard: dashboard, expiresAt: now.Add(s.cacheTTL)}
+		s.mu.Unlock()
+	}
+	return dashboard, nil
+}
+
+func (s *DashboardService) computeDashboard(options *dtos.DashboardOptions, now time.Time) (*dtos.Dashboard, error) {
+	// The window covers today and the options.Days-1 days before it
+	from := truncatePeriod(now, dtos.MovementPeriodDay).AddDate(0, 0, 1-options.Days)
+
+	summary, err := s.repo.GetStockSummary(s.lowStockThreshold)
+	if err != nil {
+		return nil, err
+	}
+	movers, err := s.repo.GetTopMovers(from, options.UserID, options.Top)
+	if err != nil {
+		return nil, err
+	}
+	daily, err := s.repo.GetDailyMovements(from, options.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &dtos.Dashboard{
+		GeneratedAt:       now.Format(time.RFC3339),
+		Scope:             dtos.DashboardScopeAll,
+		Days:              options.Days,
+		From:              from.Format(time.RFC3339),
+		ProductCount:      summary.ProductCount,
+		TotalQuantity:     summary.TotalQuantity,
+		TotalStockValue:   roundMoney(summary.TotalValue),
+		StockoutCount:     summary.StockoutCount,
+		LowStockCount:     summary.LowStockCount,
+		LowStockThreshold: s.lowStockThreshold,
+		TopMovers:         make([]dtos.DashboardMover, 0, len(movers)),
+		Trend:             make([]dtos.DashboardTrendPoint, 0, options.Days),
+	}
+
+	// Turnover and days of supply need every user's movements
+	if options.UserID != nil {
+		dashboard.Scope = dtos.DashboardScopeOwn
+	} else {
+		totals, err := s.repo.GetMovementValueTotals(from)
+		if err != nil {
+			return nil, err
+		}
+		// Stock value at the start of the window is the current value less the
+		// net change since, both at current prices
+		openingValue := summary.TotalValue - totals.NetValue
+		averageValue := (openingValue + summary.TotalValue) / 2
+		if averageValue > 0 {
+			turnover := roundShare(totals.OutValue / averageValue)
+			dashboard.TurnoverRatio = &turnover
+		}
+		if totals.OutQuantity > 0 {
+			daysOfSupply := roundShare(float64(summary.TotalQuantity) / (float64(totals.OutQuantity) / float64(options.Days)))
+			dashboard.DaysOfSupply = &daysOfSupply
+		}
+	}
+
+	for _, mover := range movers {
+		dashboard.TopMovers = append(dashboard.TopMovers, dtos.DashboardMover{
+			ProductID:   mover.ProductID,
+			SKU:         mover.SKU,
+			Name:        mover.Name,
+			OutQuantity: mover.OutQuantity,
+			OutValue:    roundMoney(mover.OutValue),
+		})
+	}
+
+	// Fill the days without movements with zeros
+	byDay := make(map[string]dtos.DashboardTrendPoint, len(daily))
+	for _, day := range daily {
+		date := day.Day.Format(time.DateOnly)
+		byDay[date] = dtos.DashboardTrendPoint{Date: date, InQuantity: day.InQuantity, OutQuantity: day.OutQuantity}
+	}
+	for day := from; day.Before(now); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		point, ok := byDay[date]
+		if !ok {
+			point = dtos.DashboardTrendPoint{Date: date}
+		}
+		dashboard.Trend = append(dashboard.Trend, point)
+	}
+	return dashboard, nil
+}
+
+func dashboardScopeKey(options *dtos.DashboardOptions) string {
+	if options.UserID == nil {
+		return dtos.DashboardScopeAll
+	}
+	return fmt.Sprintf("%s:%d", dtos.DashboardScopeOwn, *options.UserID)
+}
@@ -0,0 +1,44 @@
+package services
+
+import "errors"
+
+var (
+	// ErrVersionMismatch is returned when an update's expected version (If-Match) is stale
+	ErrVersionMismatch = errors.New("resource has been modified since it was last retrieved")
+
+	// ErrSKUConflict is returned when another active product already uses the SKU
+	ErrSKUConflict = errors.New("another product with this SKU already exists")
+
+	// ErrCategoryNotFound is returned when a product refers to a category that does not exist
+	ErrCategoryNotFound = errors.New("category not found")
+
+	// ErrProductHasTransactions is returned when purging a product still referenced by transactions
+	ErrProductHasTransactions = errors.New("product has transactions and cannot be purged")
+
+	// ErrInvalidCursor is returned when a pagination cursor is malformed or belongs to another sort order
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+	// ErrCursorWithOffset is returned when a list request sends both cursor and offset
+	ErrCursorWithOffset = errors.New("cursor cannot be combined with offset")
+
+	// ErrCursorNotSupported is returned for cursors on relevance-ranked search results
+	ErrCursorNotSupported = errors.New("cursor pagination requires an explicit sort when searching")
+
+	// ErrBulkTarget is returned when a bulk operation gives both or neither of ids and filter
+	ErrBulkTarget = errors.New("give either ids or a non-empty filter")
+
+	// ErrInvalidBulkOperation is returned when a bulk operation is missing or has invalid parameters
+	ErrInvalidBulkOperation = errors.New("invalid bulk operation")
+
+	// ErrBulkTooManyProducts is returned when a bulk operation's filter matches more products than allowed
+	ErrBulkTooManyProducts = errors.New("bulk operation matches too many products, narrow the filter")
+
+	// ErrInvalidABCOptions is returned when ABC thresholds are out of order
+	ErrInvalidABCOptions = errors.New("b_threshold must be greater than a_threshold and at most 100")
+
+	// ErrProductNotFound is returned when a report is requested for a product that does not exist
+	ErrProductNotFound = errors.New("product not found")
+
+	// ErrInvalidReportRange is returned when a report's date range is empty or too long for its periods
+	ErrInvalidReportRange = errors.New("invalid report range")
+)
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"inventory-api/utils"
+	"io"
+	"time"
+)
+
+// Export formats for products and transactions
+const (
+	ExportFormatCSV    = utils.TableFormatCSV
+	ExportFormatXLSX   = utils.TableFormatXLSX
+	ExportFormatNDJSON = "ndjson"
+)
+
+var productExportHeader = []interface{}{
+	"id", "sku", "name", "description", "price", "quantity", "status", "category_id", "abc_class", "version", "created_at", "updated_at", "deleted_at",
+}
+
+var transactionExportHeader = []interface{}{
+	"id", "created_at", "product_id", "product_sku", "product_name", "transaction_type", "quantity", "reference", "notes", "created_by", "created_by_username",
+}
+
+// ExportService streams products and transactions to a writer. Every export
+// reads one database snapshot through a cursor, so memory use does not grow
+// with the number of rows.
+type ExportService struct {
+	repo *repo.InventoryRepository
+}
+
+func NewExportService(repo *repo.InventoryRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// ExportProducts writes every product matching filter in ID order. progress
+// may be nil; otherwise it is called as rows are written.
+func (s *ExportService) ExportProducts(filter *dtos.ProductFilter, format string, w io.Writer, progress ProgressFunc) error {
+	tracker, err := s.newExportProgress(progress, func() (int64, error) {
+		return s.repo.CountProductsWithFilter(filter)
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == ExportFormatNDJSON {
+		encoder := json.NewEncoder(w)
+		return s.repo.EachProduct(filter, func(product *models.Product) error {
+			if err := tracker.row(); err != nil {
+				return err
+			}
+			return encoder.Encode(dtos.ToProductResponse(product))
+		})
+	}
+
+	return writeTable(w, format, productExportHeader, func(write func([]interface{}) error) error {
+		return s.repo.EachProduct(filter, func(product *models.Product) error {
+			if err := tracker.row(); err != nil {
+				return err
+			}
+			var deletedAt interface{}
+			if product.DeletedAt.Valid {
+				deletedAt = product.DeletedAt.Time
+			}
+			return write([]interface{}{
+				product.ID,
+				product.SKU,
+				product.Name,
+				product.Description,
+				product.Price,
+				product.Quantity,
+				string(product.Status),
+				optionalCell(product.CategoryID),
+				product.ABCClass,
+				product.Version,
+				product.CreatedAt,
+				product.UpdatedAt,
+				deletedAt,
+			})
+		})
+	})
+}
+
+// ExportTransactions writes every transaction matching filter oldest first,
+// with the product SKU and name and the recording user's name. progress may be
+// nil; otherwise it is called as rows are written.
+func (s *ExportService) ExportTransactions(filter *dtos.TransactionFilter, format string, w io.Writer, progress ProgressFunc) error {
+	tracker, err := s.newExportProgress(progress, func() (int64, error) {
+		return s.repo.CountTransactionsWithFilter(filter)
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == ExportFormatNDJSON {
+		encoder := json.NewEncoder(w)
+		return s.repo.EachTransactionLedgerRow(filter, func(row *models.TransactionLedgerRow) error {
+			if err := tracker.row(); err != nil {
+				return err
+			}
+			return encoder.Encode(dtos.ToTransactionExportRecord(row))
+		})
+	}
+
+	return writeTable(w, format, transactionExportHeader, func(write func([]interface{}) error) error {
+		return s.repo.EachTransactionLedgerRow(filter, func(row *models.TransactionLedgerRow) error {
+			if err := tracker.row(); err != nil {
+				return err
+			}
+			return write([]interface{}{
+				row.Transaction.ID,
+				row.Transaction.CreatedAt,
+				row.Transaction.ProductID,
+				row.ProductSKU,
+				row.ProductName,
+				string(row.Transaction.TransactionType),
+				row.Transaction.Quantity,
+				row.Transaction.Reference,
+				row.Transaction.Notes,
+				optionalCell(row.Transaction.CreatedBy),
+				row.CreatorName,
+			})
+		})
+	})
+}
+
+// ProductExportJob runs a product_export job, storing the file as its result
+func (s *ExportService) ProductExportJob(run *JobRun) error {
+	var params dtos.ProductExportJobParams
+	if err := run.Params(&params); err != nil {
+		return err
+	}
+	return run.WriteResult(ExportFileName("products", params.Format), ExportContentType(params.Format), func(w io.Writer) error {
+		return s.ExportProducts(&params.Filter, params.Format, w, run.Progress)
+	})
+}
+
+// TransactionExportJob runs a transaction_export job, storing the file as its result
+func (s *ExportService) TransactionExportJob(run *JobRun) error {
+	var params dtos.TransactionExportJobParams
+	if err := run.Params(&params); err != nil {
+		return err
+	}
+	return run.WriteResult(ExportFileName("transactions", params.Format), ExportContentType(params.Format), func(w io.Writer) error {
+		return s.ExportTransactions(&params.Filter, params.Format, w, run.Progress)
+	})
+}
+
+// ExportFileName names an export file after its content and the current time
+func ExportFileName(name, format string) string {
+	return fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102-150405"), format)
+}
+
+// exportProgressStep is how many rows are written between progress reports
+const exportProgressStep = 1000
+
+// exportProgress reports export progress every exportProgressStep rows
+type exportProgress struct {
+	progress ProgressFunc
+	total    int
+	done     int
+}
+
+// newExportProgress counts the rows to export when progress is reported at all.
+// The count is taken outside the export snapshot, so it is an estimate.
+func (s *ExportService) newExportProgress(progress ProgressFunc, count func() (int64, error)) (*exportProgress, error) {
+	tracker := &exportProgress{progress: progress}
+	if progress == nil {
+		return tracker, nil
+	}
+	total, err := count()
+	if err != nil {
+		return nil, err
+	}
+	tracker.total = int(total)
+	return tracker, progress(0, tracker.total)
+}
+
+func (p *exportProgress) row() error {
+	p.done++
+	if p.progress == nil || p.done%exportProgressStep != 0 {
+		return nil
+	}
+	return p.progress(min(p.done, p.total), p.total)
+}
+
+// writeTable writes header and the rows produced by each as CSV or XLSX
+func writeTable(w io.Writer, format string, header []interface{}, each func(write func([]interface{}) error) error) error {
+	table, err := utils.NewTableWriter(w, format)
+	if errors.Is(err, utils.ErrUnsupportedTableFormat) {
+		return ErrUnsupportedExportFormat
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := table.WriteRow(header); err != nil {
+		table.Abort()
+		return err
+	}
+	if err := each(table.WriteRow); err != nil {
+		table.Abort()
+		return err
+	}
+	return table.Close()
+}
+
+// optionalCell leaves the cell empty for a missing ID
+func optionalCell(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
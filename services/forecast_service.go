@@ -0,0 +1,388 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// forecastBatchSize is how many products a forecast job loads and saves at once
+const forecastBatchSize = 500
+
+// forecastZ is the standard normal quantile of a 95% prediction interval
+const forecastZ = 1.96
+
+// forecastSeason is the season length of daily demand, one week
+const forecastSeason = 7
+
+// maxForecastHoldout caps how many trailing days of history are held out to backtest models
+const maxForecastHoldout = 28
+
+// holtWintersDamping flattens the Holt-Winters trend so long horizons do not
+// extrapolate it without bound
+const holtWintersDamping = 0.9
+
+// forecastFunc forecasts the next horizon days of a fitted series
+type forecastFunc func(horizon int) []float64
+
+// forecastCandidate is a model tried on every product. minHistory is the fewest
+// days it can be fitted on.
+type forecastCandidate struct {
+	name       string
+	minHistory int
+	fit        func(history []float64) forecastFunc
+}
+
+// forecastCandidates are tried in order; on equal backtest error the earlier,
+// simpler model wins
+var forecastCandidates = []forecastCandidate{
+	{dtos.ForecastModelMovingAverage7, 7, fitMovingAverage(7)},
+	{dtos.ForecastModelMovingAverage28, 28, fitMovingAverage(28)},
+	{dtos.ForecastModelHoltWinters, 2 * forecastSeason, fitHoltWinters},
+}
+
+// ForecastService forecasts the daily OUT demand of products from their
+// transaction history and stores the latest forecast of each product
+type ForecastService struct {
+	repo        *repo.ForecastRepository
+	historyDays int
+}
+
+func NewForecastService(repo *repo.ForecastRepository, historyDays int) *ForecastService {
+	return &ForecastService{repo: repo, historyDays: historyDays}
+}
+
+// GetProductForecast returns the first horizon days of the product's demand
+// forecast. Forecasts are recalculated nightly; when the stored one was not made
+// today, the product is forecast now.
+func (s *ForecastService) GetProductForecast(productID uint, horizon int) (*dtos.ProductForecast, error) {
+	product, err := s.repo.GetProductByID(productID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	today := truncatePeriod(time.Now().UTC(), dtos.MovementPeriodDay)
+	forecast, err := s.repo.GetForecast(productID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if forecast == nil || forecast.GeneratedAt.Before(today) {
+		forecasts, err := s.forecastProducts([]models.Product{*product}, today)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.SaveForecasts(forecasts); err != nil {
+			return nil, err
+		}
+		forecast = &forecasts[0]
+	}
+
+	response := &dtos.ProductForecast{
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		Name:        product.Name,
+		Model:       forecast.Model,
+		GeneratedAt: forecast.GeneratedAt.UTC().Format(time.RFC3339),
+		HistoryDays: forecast.HistoryDays,
+	}
+	if err := json.Unmarshal([]byte(forecast.Candidates), &response.Candidates); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(forecast.Points), &response.Points); err != nil {
+		return nil, err
+	}
+	if len(response.Points) > horizon {
+		response.Points = response.Points[:horizon]
+	}
+	return response, nil
+}
+
+// ProductForecastJob runs a product_forecast job, recalculating and storing the
+// forecasts of every product that is not archived, or of the given products
+func (s *ForecastService) ProductForecastJob(run *JobRun) error {
+	var params dtos.ProductForecastJobParams
+	if err := run.Params(&params); err != nil {
+		return err
+	}
+
+	total, err := s.repo.CountForecastProducts(params.ProductIDs)
+	if err != nil {
+		return err
+	}
+
+	today := truncatePeriod(time.Now().UTC(), dtos.MovementPeriodDay)
+	counts := make(map[string]int)
+	done := 0
+	var afterID uint
+	for {
+		products, err := s.repo.GetForecastProducts(params.ProductIDs, afterID, forecastBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			break
+		}
+
+		forecasts, err := s.forecastProducts(products, today)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SaveForecasts(forecasts); err != nil {
+			return err
+		}
+		for _, forecast := range forecasts {
+			counts[forecast.Model]++
+		}
+
+		done += len(products)
+		afterID = products[len(products)-1].ID
+		if err := run.Progress(done, int(total)); err != nil {
+			return err
+		}
+	}
+
+	return run.SetSummary(map[string]interface{}{"products": done, "models": counts})
+}
+
+// forecastProducts forecasts the demand of the products from today on, fitting
+// on their daily OUT quantities since historyDays ago or since they were created
+func (s *ForecastService) forecastProducts(products []models.Product, today time.Time) ([]models.ProductForecast, error) {
+	from := today.AddDate(0, 0, -s.historyDays)
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	demand, err := s.repo.GetDailyDemand(ids, from, today)
+	if err != nil {
+		return nil, err
+	}
+	byProduct := make(map[uint]map[int64]float64)
+	for _, day := range demand {
+		if byProduct[day.ProductID] == nil {
+			byProduct[day.ProductID] = make(map[int64]float64)
+		}
+		byProduct[day.ProductID][day.Day.Unix()] = day.Quantity
+	}
+
+	generatedAt := time.Now()
+	forecasts := make([]models.ProductForecast, 0, len(products))
+	for _, product := range products {
+		start := from
+		if created := truncatePeriod(product.CreatedAt.UTC(), dtos.MovementPeriodDay); created.After(start) {
+			start = created
+		}
+		history := []float64{}
+		for day := start; day.Before(today); day = day.AddDate(0, 0, 1) {
+			history = append(history, byProduct[product.ID][day.Unix()])
+		}
+
+		result := forecastDemand(history, dtos.MaxForecastHorizon)
+		pointsJSON, err := json.Marshal(forecastPoints(result, today))
+		if err != nil {
+			return nil, err
+		}
+		candidatesJSON, err := json.Marshal(result.scores)
+		if err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, models.ProductForecast{
+			ProductID:   product.ID,
+			Model:       result.model,
+			HistoryDays: len(history),
+			Points:      string(pointsJSON),
+			Candidates:  string(candidatesJSON),
+			GeneratedAt: generatedAt,
+		})
+	}
+	return forecasts, nil
+}
+
+// forecastPoints dates the forecast values from today on and adds their 95%
+// prediction intervals. The error of a forecast grows with the days ahead, so the
+// interval of day k is widened by the square root of k.
+func forecastPoints(result demandForecast, today time.Time) []dtos.ForecastPoint {
+	points := make([]dtos.ForecastPoint, len(result.values))
+	for i, value := range result.values {
+		spread := forecastZ * result.sigma * math.Sqrt(float64(i+1))
+		points[i] = dtos.ForecastPoint{
+			Date:     today.AddDate(0, 0, i).Format(time.DateOnly),
+			Quantity: roundShare(math.Max(value, 0)),
+			Lower:    roundShare(math.Max(value-spread, 0)),
+			Upper:    roundShare(math.Max(value+spread, 0)),
+		}
+	}
+	return points
+}
+
+// demandForecast is the forecast of the chosen model with the standard
+// deviation of its errors, used for prediction intervals
+type demandForecast struct {
+	model  string
+	values []float64
+	sigma  float64
+	scores []dtos.ForecastModelScore
+}
+
+// forecastDemand backtests every candidate on the last days of history, refits
+// the one with the lowest mean absolute error on all of history and forecasts
+// horizon days. Histories too short to backtest are forecast with a moving
+// average of what there is.
+func forecastDemand(history []float64, horizon int) demandForecast {
+	result := demandForecast{scores: []dtos.ForecastModelScore{}}
+
+	best, bestMAE := -1, 0.0
+	holdout := min(max(len(history)/4, forecastSeason), maxForecastHoldout)
+	if len(history) > holdout {
+		train, test := history[:len(history)-holdout], history[len(history)-holdout:]
+		for i, candidate := range forecastCandidates {
+			if len(train) < candidate.minHistory {
+				continue
+			}
+			mae, rmse := forecastErrors(candidate.fit(train)(holdout), test)
+			result.scores = append(result.scores, dtos.ForecastModelScore{Model: candidate.name, MAE: roundShare(mae), RMSE: roundShare(rmse)})
+			if best < 0 || mae < bestMAE {
+				best, bestMAE, result.sigma = i, mae, rmse
+			}
+		}
+	}
+
+	if best < 0 {
+		result.model = dtos.ForecastModelMovingAverage7
+		result.values = fitMovingAverage(forecastSeason)(history)(horizon)
+		result.sigma = standardDeviation(history)
+		return result
+	}
+	result.model = forecastCandidates[best].name
+	result.values = forecastCandidates[best].fit(history)(horizon)
+	return result
+}
+
+// forecastErrors returns the mean absolute and root mean squared error of a
+// forecast, counting negative forecast demand as zero
+func forecastErrors(forecast, actual []float64) (float64, float64) {
+	var absolute, squared float64
+	for i, value := range actual {
+		diff := math.Max(forecast[i], 0) - value
+		absolute += math.Abs(diff)
+		squared += diff * diff
+	}
+	n := float64(len(actual))
+	return absolute / n, math.Sqrt(squared / n)
+}
+
+// fitMovingAverage forecasts every day as the mean of the last window days
+func fitMovingAverage(window int) func(history []float64) forecastFunc {
+	return func(history []float64) forecastFunc {
+		level := mean(history[max(len(history)-window, 0):])
+		return func(horizon int) []float64 {
+			values := make([]float64, horizon)
+			for i := range values {
+				values[i] = level
+			}
+			return values
+		}
+	}
+}
+
+// holtWinters is the state of additive Holt-Winters exponential smoothing after
+// the last day of history
+type holtWinters struct {
+	level  float64
+	trend  float64
+	season []float64 // indexed by day of history modulo the season length
+	next   int       // season index of the first forecast day
+}
+
+// fitHoltWinters fits additive Holt-Winters exponential smoothing with a damped
+// trend and a weekly season, picking the smoothing parameters from a small grid
+// by one-step-ahead squared error. history must cover two seasons.
+func fitHoltWinters(history []float64) forecastFunc {
+	var best *holtWinters
+	bestSSE := math.Inf(1)
+	for _, alpha := range []float64{0.1, 0.2, 0.4, 0.6} {
+		for _, beta := range []float64{0.01, 0.1, 0.2} {
+			for _, gamma := range []float64{0.05, 0.1, 0.3} {
+				model, sse := runHoltWinters(history, alpha, beta, gamma)
+				if sse < bestSSE {
+					best, bestSSE = model, sse
+				}
+			}
+		}
+	}
+	return best.forecast
+}
+
+// runHoltWinters smooths history with the given parameters, starting from the
+// level, trend and season of its first two seasons. The first season only warms
+// the model up and does not count towards the squared error.
+func runHoltWinters(history []float64, alpha, beta, gamma float64) (*holtWinters, float64) {
+	first := mean(history[:forecastSeason])
+	second := mean(history[forecastSeason : 2*forecastSeason])
+	model := &holtWinters{
+		level:  first,
+		trend:  (second - first) / forecastSeason,
+		season: make([]float64, forecastSeason),
+	}
+	for i := range model.season {
+		model.season[i] = history[i] - first
+	}
+
+	sse := 0.0
+	for t, value := range history {
+		season := model.season[t%forecastSeason]
+		if t >= forecastSeason {
+			diff := value - (model.level + holtWintersDamping*model.trend + season)
+			sse += diff * diff
+		}
+		lastLevel := model.level
+		model.level = alpha*(value-season) + (1-alpha)*(model.level+holtWintersDamping*model.trend)
+		model.trend = beta*(model.level-lastLevel) + (1-beta)*holtWintersDamping*model.trend
+		model.season[t%forecastSeason] = gamma*(value-model.level) + (1-gamma)*season
+	}
+	model.next = len(history) % forecastSeason
+	return model, sse
+}
+
+func (m *holtWinters) forecast(horizon int) []float64 {
+	values := make([]float64, horizon)
+	damping, factor := 0.0, 1.0
+	for k := range values {
+		factor *= holtWintersDamping
+		damping += factor
+		values[k] = m.level + damping*m.trend + m.season[(m.next+k)%forecastSeason]
+	}
+	return values
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	avg := mean(values)
+	squared := 0.0
+	for _, value := range values {
+		squared += (value - avg) * (value - avg)
+	}
+	return math.Sqrt(squared / float64(len(values)))
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"inventory-api/dtos"
+	"math"
+	"testing"
+	"time"
+)
+
+// Test series of daily demand
+func constantSeries(days int, value float64) []float64 {
+	series := make([]float64, days)
+	for i := range series {
+		series[i] = value
+	}
+	return series
+}
+
+func trendingSeries(days int) []float64 {
+	series := make([]float64, days)
+	for i := range series {
+		series[i] = 10 + float64(i)
+	}
+	return series
+}
+
+// weeklyPattern is the demand of each day of the week in weeklySeries
+var weeklyPattern = []float64{2, 4, 6, 20, 6, 4, 2}
+
+func weeklySeries(days int) []float64 {
+	series := make([]float64, days)
+	for i := range series {
+		series[i] = weeklyPattern[i%forecastSeason]
+	}
+	return series
+}
+
+func almostEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestForecastErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		forecast []float64
+		actual   []float64
+		mae      float64
+		rmse     float64
+	}{
+		{"exact", []float64{1, 2, 3}, []float64{1, 2, 3}, 0, 0},
+		{"misses", []float64{1, 2, 3}, []float64{2, 2, 5}, 1, math.Sqrt(5.0 / 3)},
+		{"negative forecast counts as zero", []float64{-4, 1}, []float64{0, 1}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mae, rmse := forecastErrors(tt.forecast, tt.actual)
+			if !almostEqual(mae, tt.mae, 1e-9) || !almostEqual(rmse, tt.rmse, 1e-9) {
+				t.Errorf("forecastErrors = %v, %v, want %v, %v", mae, rmse, tt.mae, tt.rmse)
+			}
+		})
+	}
+}
+
+func TestRunHoltWinters(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []float64
+		// want is the expected forecast of the next week, nil to only check the trend
+		want   []float64
+		maxSSE float64
+	}{
+		{"constant", constantSeries(28, 5), constantSeries(forecastSeason, 5), 1e-9},
+		{"weekly seasonal", weeklySeries(28), weeklyPattern, 1e-9},
+		{"weekly seasonal, not a whole number of weeks", weeklySeries(31), append(weeklyPattern[3:], weeklyPattern[:3]...), 1e-9},
+		{"trending", trendingSeries(28), nil, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, sse := runHoltWinters(tt.history, 0.4, 0.1, 0.1)
+			if sse > tt.maxSSE {
+				t.Errorf("sse = %v, want at most %v", sse, tt.maxSSE)
+			}
+			values := model.forecast(forecastSeason)
+			if tt.want == nil {
+				lastWeek := mean(tt.history[len(tt.history)-forecastSeason:])
+				if mean(values) <= lastWeek || values[len(values)-1] <= values[0] {
+					t.Errorf("forecast = %v after a week averaging %v, want it to keep rising", values, lastWeek)
+				}
+				return
+			}
+			for k, want := range tt.want {
+				if !almostEqual(values[k], want, 1e-6) {
+					t.Errorf("forecast = %v, want %v", values, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestForecastDemand(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []float64
+		model   string
+		scores  int
+		// check validates the forecast of the next two weeks
+		check func(t *testing.T, values []float64)
+	}{
+		{
+			name: "constant", history: constantSeries(90, 5),
+			// Every candidate is exact, so the simplest one wins
+			model: dtos.ForecastModelMovingAverage7, scores: 3,
+			check: func(t *testing.T, values []float64) {
+				for _, value := range values {
+					if !almostEqual(value, 5, 1e-9) {
+						t.Fatalf("forecast = %v, want 5 every day", values)
+					}
+				}
+			},
+		},
+		{
+			name: "trending", history: trendingSeries(90),
+			model: dtos.ForecastModelHoltWinters, scores: 3,
+			check: func(t *testing.T, values []float64) {
+				if values[0] < 95 || values[len(values)-1] <= values[0] {
+					t.Errorf("forecast = %v, want it to keep rising from 99", values)
+				}
+			},
+		},
+		{
+			name: "weekly seasonal", history: weeklySeries(84),
+			model: dtos.ForecastModelHoltWinters, scores: 3,
+			check: func(t *testing.T, values []float64) {
+				for k, value := range values {
+					if want := weeklyPattern[k%forecastSeason]; !almostEqual(value, want, 0.5) {
+						t.Fatalf("forecast = %v, want the weekly pattern %v", values, weeklyPattern)
+					}
+				}
+			},
+		},
+		{
+			name: "shorter than two seasons", history: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			// Too short to backtest anything: the mean of the last week
+			model: dtos.ForecastModelMovingAverage7, scores: 0,
+			check: func(t *testing.T, values []float64) {
+				for _, value := range values {
+					if !almostEqual(value, 7, 1e-9) {
+						t.Fatalf("forecast = %v, want 7 every day", values)
+					}
+				}
+			},
+		},
+		{
+			name: "no history", history: []float64{},
+			model: dtos.ForecastModelMovingAverage7, scores: 0,
+			check: func(t *testing.T, values []float64) {
+				for _, value := range values {
+					if value != 0 {
+						t.Fatalf("forecast = %v, want 0 every day", values)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := forecastDemand(tt.history, 2*forecastSeason)
+			if result.model != tt.model {
+				t.Errorf("model = %s, want %s (scores %+v)", result.model, tt.model, result.scores)
+			}
+			if len(result.scores) != tt.scores {
+				t.Errorf("scores = %+v, want %d", result.scores, tt.scores)
+			}
+			if len(result.values) != 2*forecastSeason {
+				t.Fatalf("forecast has %d days, want %d", len(result.values), 2*forecastSeason)
+			}
+			tt.check(t, result.values)
+		})
+	}
+}
+
+func TestForecastPointsWidenWithHorizon(t *testing.T) {
+	result := demandForecast{values: constantSeries(4, 10), sigma: 2}
+	points := forecastPoints(result, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	if points[0].Date != "2024-03-01" || points[3].Date != "2024-03-04" {
+		t.Errorf("dates = %s..%s, want 2024-03-01..2024-03-04", points[0].Date, points[3].Date)
+	}
+	for k, point := range points {
+		spread := forecastZ * 2 * math.Sqrt(float64(k+1))
+		if !almostEqual(point.Upper, 10+spread, 0.01) || !almostEqual(point.Lower, math.Max(10-spread, 0), 0.01) {
+			t.Errorf("day %d interval = [%v, %v], want 10 ± %v", k+1, point.Lower, point.Upper, spread)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request body")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyLeaseLost  = errors.New("idempotency key was reclaimed by a retry before this request completed")
+)
+
+type IdempotencyService struct {
+	repo  *repo.IdempotencyRepository
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// NewIdempotencyService creates the service. Completed responses are replayed for
+// ttl. The lease on a pending key is renewed while its request runs, so a key only
+// stays pending past lease when the server stopped while handling the request;
+// a retry can then claim it.
+func NewIdempotencyService(repo *repo.IdempotencyRepository, ttl, lease time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl, lease: lease}
+}
+
+// Idempotent runs fn at most once per (user, operation, key). A retry with the
+// same key and request body gets the stored response back; a retry with a
+// different body fails with ErrIdempotencyKeyReused. An empty key disables the check.
+func Idempotent[T any](s *IdempotencyService, userID uint, operation, key string, request interface{}, fn func() (*T, error)) (*T, error) {
+	if s == nil || key == "" {
+		return fn()
+	}
+
+	hash, err := hashRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	record, reserved, err := s.reserve(userID, operation, key, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Key seen before: replay or reject
+	if !reserved {
+		if record.RequestHash != hash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if !record.Completed {
+			return nil, ErrIdempotencyInProgress
+		}
+		var replay T
+		if err := json.Unmarshal([]byte(record.ResponseBody), &replay); err != nil {
+			return nil, errors.New("failed to replay stored response")
+		}
+		return &replay, nil
+	}
+
+	stopRenewing := s.renewLease(record.ID)
+	result, err := fn()
+	stopRenewing()
+	if err != nil {
+		// Release the key so the client can retry after a failure
+		_ = s.repo.DeleteKey(record.ID)
+		return nil, err
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.repo.CompleteKey(record.ID, string(body), time.Now().Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrIdempotencyLeaseLost
+	}
+	return result, nil
+}
+
+// renewLease extends the lease on the reserved key every third of the lease until
+// the returned function is called, which waits for renewal to stop
+func (s *IdempotencyService) renewLease(id uint) func() {
+	interval := s.lease / 3
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// A failed renewal is retried on the next tick; a lost lease is
+				// reported when the key is completed
+				held, err := s.repo.RenewKey(id, time.Now().Add(s.lease))
+				if err == nil && !held {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// reserve claims the key for this request, or returns the existing record. The
+// claim is a lease: once it runs out the key counts as expired and a retry
+// removes it and claims the key itself.
+func (s *IdempotencyService) reserve(userID uint, operation, key, hash string) (*models.IdempotencyKey, bool, error) {
+	now := time.Now()
+	if err := s.repo.DeleteExpiredKey(userID, operation, key, now); err != nil {
+		return nil, false, err
+	}
+
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Operation:   operation,
+		Key:         key,
+		RequestHash: hash,
+		ExpiresAt:   now.Add(s.lease),
+	}
+	reserved, err := s.repo.ReserveKey(record)
+	if err != nil {
+		return nil, false, err
+	}
+	if reserved {
+		return record, true, nil
+	}
+
+	existing, err := s.repo.GetKey(userID, operation, key)
+	if err != nil {
+		// The other request failed and released the key in the meantime
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrIdempotencyInProgress
+		}
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// PurgeExpired deletes all expired idempotency keys
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpired(time.Now())
+}
+
+func hashRequest(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
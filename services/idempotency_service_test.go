@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"inventory-api/database/databasetest"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type idempotencyResult struct {
+	Value int `json:"value"`
+}
+
+func newTestIdempotencyService(t *testing.T) (*IdempotencyService, *gorm.DB, uint) {
+	t.Helper()
+	db := databasetest.Open(t)
+	// Keys are not tied to a users row, so any otherwise unused ID scopes them to this test
+	userID := uint(time.Now().UnixNano() % 1_000_000_000)
+	t.Cleanup(func() {
+		db.Where("user_id = ?", userID).Delete(&models.IdempotencyKey{})
+	})
+	return NewIdempotencyService(repo.NewIdempotencyRepository(db), time.Hour, time.Minute), db, userID
+}
+
+// insertPendingKey stores a key as a request that never finished would leave it
+func insertPendingKey(t *testing.T, db *gorm.DB, userID uint, request interface{}, expiresAt time.Time) {
+	t.Helper()
+	hash, err := hashRequest(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &models.IdempotencyKey{UserID: userID, Operation: "test", Key: "k", RequestHash: hash, ExpiresAt: expiresAt}
+	if err := db.Create(key).Error; err != nil {
+		t.Fatalf("insert key: %v", err)
+	}
+}
+
+func TestIdempotentReplaysCompletedKey(t *testing.T) {
+	s, db, userID := newTestIdempotencyService(t)
+	calls := 0
+	fn := func() (*idempotencyResult, error) {
+		calls++
+		return &idempotencyResult{Value: calls}, nil
+	}
+
+	for range 2 {
+		result, err := Idempotent(s, userID, "test", "k", "body", fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Value != 1 {
+			t.Errorf("result = %d, want the first response", result.Value)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fn ran %d times, want 1", calls)
+	}
+
+	// Completing the key extends it from the lease to the full TTL
+	var key models.IdempotencyKey
+	if err := db.Where("user_id = ?", userID).First(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(key.ExpiresAt) < 30*time.Minute {
+		t.Errorf("completed key expires in %s, want about the TTL", time.Until(key.ExpiresAt))
+	}
+}
+
+func TestIdempotentPendingKeyWithinLease(t *testing.T) {
+	s, db, userID := newTestIdempotencyService(t)
+	insertPendingKey(t, db, userID, "body", time.Now().Add(time.Minute))
+
+	_, err := Idempotent(s, userID, "test", "k", "body", func() (*idempotencyResult, error) {
+		t.Fatal("fn ran while another request holds the key")
+		return nil, nil
+	})
+	if !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Fatalf("err = %v, want ErrIdempotencyInProgress", err)
+	}
+}
+
+func TestIdempotentReclaimsExpiredLease(t *testing.T) {
+	s, db, userID := newTestIdempotencyService(t)
+	insertPendingKey(t, db, userID, "body", time.Now().Add(-time.Second))
+
+	result, err := Idempotent(s, userID, "test", "k", "body", func() (*idempotencyResult, error) {
+		return &idempotencyResult{Value: 7}, nil
+	})
+	if err != nil {
+		t.Fatalf("retry after the lease ran out: %v", err)
+	}
+	if result.Value != 7 {
+		t.Errorf("result = %d, want 7", result.Value)
+	}
+}
+
+func TestIdempotentRenewsLeaseWhileRunning(t *testing.T) {
+	s, db, userID := newTestIdempotencyService(t)
+	s = NewIdempotencyService(s.repo, time.Hour, 300*time.Millisecond)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := Idempotent(s, userID, "test", "k", "body", func() (*idempotencyResult, error) {
+			close(started)
+			<-release
+			return &idempotencyResult{Value: 1}, nil
+		})
+		done <- err
+	}()
+	<-started
+
+	// Well past the original lease, the running request must still hold the key
+	time.Sleep(time.Second)
+	_, err := Idempotent(s, userID, "test", "k", "body", func() (*idempotencyResult, error) {
+		t.Error("retry ran while the first request was still running")
+		return nil, nil
+	})
+	if !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("retry err = %v, want ErrIdempotencyInProgress", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	var key models.IdempotencyKey
+	if err := db.Where("user_id = ?", userID).First(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !key.Completed {
+		t.Error("key not completed")
+	}
+}
+
+func TestIdempotentReportsLostLease(t *testing.T) {
+	s, db, userID := newTestIdempotencyService(t)
+
+	_, err := Idempotent(s, userID, "test", "k", "body", func() (*idempotencyResult, error) {
+		// A retry removed the key after the lease ran out and claimed it anew
+		if err := db.Where("user_id = ?", userID).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		insertPendingKey(t, db, userID, "body", time.Now().Add(time.Minute))
+		return &idempotencyResult{Value: 1}, nil
+	})
+	if !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Fatalf("err = %v, want ErrIdempotencyLeaseLost", err)
+	}
+
+	// The retry's claim must be left alone
+	var key models.IdempotencyKey
+	if err := db.Where("user_id = ?", userID).First(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+	if key.Completed {
+		t.Error("the retry's pending key was completed by the first request")
+	}
+}
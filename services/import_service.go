@@ -0,0 +1,513 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"inventory-api/utils"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// importChunkSize is how many rows are written per database transaction
+const importChunkSize = 500
+
+// maxProductPrice is the largest price the products.price column can hold
+const maxProductPrice = 99999999.99
+
+// ErrInvalidImportFile is returned when an import file cannot be read as a whole,
+// as opposed to individual rows failing validation
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+type ImportService struct {
+	repo        *repo.InventoryRepository
+	audit       *AuditService
+	jobs        *JobService
+	maxFileSize int64
+}
+
+func NewImportService(repo *repo.InventoryRepository, audit *AuditService, jobs *JobService, maxFileSize int64) *ImportService {
+	return &ImportService{repo: repo, audit: audit, jobs: jobs, maxFileSize: maxFileSize}
+}
+
+func (s *ImportService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+// importRow is a data row of an import file with its cells keyed by product field
+type importRow struct {
+	number int
+	values map[string]string
+}
+
+// importPlan is the write planned for a valid row
+type importPlan struct {
+	result *dtos.ImportRowResult
+	create *models.Product
+	change *repo.ProductChange
+}
+
+// ImportProducts creates or updates products from a CSV or XLSX file, matching
+// existing products by SKU. Every row is validated first; unless options.DryRun is
+// set the valid rows are then written in chunks, each in its own transaction.
+// progress may be nil; otherwise it is called after every chunk, and the import
+// stops before the next chunk when it returns an error.
+func (s *ImportService) ImportProducts(file io.Reader, fileName string, size int64, options dtos.ImportOptions, actor Actor, progress ProgressFunc) (*dtos.ImportReport, error) {
+	if size > s.maxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	reader := bufio.NewReader(file)
+	format := options.Format
+	if format == "" {
+		head, _ := reader.Peek(4)
+		format = utils.DetectTableFormat(fileName, head)
+	}
+
+	table, err := utils.OpenTable(reader, format, options.Sheet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	defer table.Close()
+
+	rows, err := readImportRows(table, options.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	categories, err := s.categoryIDs()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.existingProducts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dtos.ImportReport{
+		DryRun:    options.DryRun,
+		Format:    format,
+		TotalRows: len(rows),
+		Rows:      make([]dtos.ImportRowResult, len(rows)),
+	}
+	var plans []importPlan
+	firstSeen := make(map[string]int)
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if plan, ok := planImportRow(row, result, existing, categories, firstSeen); ok {
+			plans = append(plans, plan)
+		}
+	}
+
+	if !options.DryRun {
+		for start := 0; start < len(plans); start += importChunkSize {
+			end := min(start+importChunkSize, len(plans))
+			s.applyImportChunk(plans[start:end], actor)
+			if progress != nil {
+				if err := progress(end, len(plans)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, result := range report.Rows {
+		switch result.Action {
+		case "create":
+			report.Created++
+		case "update":
+			report.Updated++
+		case "unchanged":
+			report.Unchanged++
+		case "invalid":
+			report.Invalid++
+		case "failed":
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// EnqueueImport stores the file and imports it in a background job, for files
+// too large to import within a request
+func (s *ImportService) EnqueueImport(file io.Reader, fileName string, size int64, options dtos.ImportOptions, actor Actor) (*dtos.JobResponse, error) {
+	if size > s.maxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	params := dtos.ProductImportJobParams{FileName: fileName, Options: options}
+	return s.jobs.EnqueueWithInput(dtos.JobTypeProductImport, params, file, size, "application/octet-stream", actor)
+}
+
+// ProductImportJob runs a product_import job. The counts become the job summary
+// and the full per-row report its downloadable result.
+func (s *ImportService) ProductImportJob(run *JobRun) error {
+	var params dtos.ProductImportJobParams
+	if err := run.Params(&params); err != nil {
+		return err
+	}
+
+	input, err := run.OpenInput()
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	report, err := s.ImportProducts(input, params.FileName, 0, params.Options, run.Actor(), run.Progress)
+	if errors.Is(err, ErrInvalidImportFile) {
+		return NonRetryable(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	summary := *report
+	summary.Rows = nil
+	if err := run.SetSummary(summary); err != nil {
+		return err
+	}
+	return run.WriteResult("import-report.json", "application/json", func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(report)
+	})
+}
+
+// applyImportChunk writes one chunk of planned rows, marking them all failed if
+// the transaction is rolled back
+func (s *ImportService) applyImportChunk(plans []importPlan, actor Actor) {
+	var creates []*models.Product
+	var updates []repo.ProductChange
+	for _, plan := range plans {
+		if plan.create != nil {
+			creates = append(creates, plan.create)
+		} else {
+			updates = append(updates, *plan.change)
+		}
+	}
+
+	err := s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).ImportProducts(creates, updates, actor.UserID); err != nil {
+			return err
+		}
+
+		var created, updated []AuditChange
+		for _, plan := range plans {
+			if plan.create != nil {
+				created = append(created, AuditChange{EntityID: plan.create.ID, After: dtos.ToProductResponse(plan.create)})
+			} else {
+				updated = append(updated, AuditChange{
+					EntityID: plan.change.Product.ID,
+					Before:   dtos.ToProductResponse(plan.change.Previous),
+					After:    dtos.ToProductResponse(plan.change.Product),
+				})
+			}
+		}
+		audit.RecordAll(AuditEntityProduct, AuditActionCreate, created)
+		audit.RecordAll(AuditEntityProduct, AuditActionUpdate, updated)
+		return nil
+	})
+	if err != nil {
+		message := importWriteError(err)
+		for _, plan := range plans {
+			plan.result.Action = "failed"
+			plan.result.Errors = append(plan.result.Errors, message)
+		}
+		return
+	}
+
+	for _, plan := range plans {
+		if plan.create != nil {
+			id := plan.create.ID
+			plan.result.ProductID = &id
+		}
+	}
+}
+
+// categoryIDs maps lower-cased category names to their IDs
+func (s *ImportService) categoryIDs() (map[string]uint, error) {
+	categories, err := s.repo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(categories))
+	for _, category := range categories {
+		ids[strings.ToLower(category.Name)] = category.ID
+	}
+	return ids, nil
+}
+
+// existingProducts loads the active products whose SKUs appear in rows, keyed by SKU
+func (s *ImportService) existingProducts(rows []importRow) (map[string]*models.Product, error) {
+	var skus []string
+	for _, row := range rows {
+		if sku := row.values["sku"]; sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	slices.Sort(skus)
+	skus = slices.Compact(skus)
+
+	products := make(map[string]*models.Product, len(skus))
+	for start := 0; start < len(skus); start += importChunkSize {
+		found, err := s.repo.GetProductsBySKUs(skus[start:min(start+importChunkSize, len(skus))])
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			products[found[i].SKU] = &found[i]
+		}
+	}
+	return products, nil
+}
+
+// readImportRows reads the header and all non-blank data rows, keying cells by the
+// product field their column is mapped to
+func readImportRows(table utils.TableReader, mapping map[string]string) ([]importRow, error) {
+	header, err := table.Next()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := importColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	for number := 2; ; number++ {
+		record, err := table.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", number, err)
+		}
+
+		row := importRow{number: number, values: make(map[string]string, len(columns))}
+		blank := true
+		for field, index := range columns {
+			if index < len(record) {
+				value := strings.TrimSpace(record[index])
+				row.values[field] = value
+				blank = blank && value == ""
+			}
+		}
+		if !blank {
+			rows = append(rows, row)
+		}
+	}
+}
+
+// importColumns finds the column index of each product field. Headers match case
+// insensitively; mapping overrides the header expected for a field.
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	for field := range mapping {
+		if !slices.Contains(dtos.ProductImportColumns, field) {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[key]; !ok {
+			positions[key] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range dtos.ProductImportColumns {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		index, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("column %q mapped to %s not found", name, field)
+			}
+			continue
+		}
+		columns[field] = index
+	}
+
+	if _, ok := columns["sku"]; !ok {
+		return nil, errors.New("a sku column is required")
+	}
+	return columns, nil
+}
+
+// planImportRow validates a row and decides whether it creates, updates or leaves
+// a product unchanged. It returns false when there is nothing to write.
+func planImportRow(row importRow, result *dtos.ImportRowResult, existing map[string]*models.Product, categories map[string]uint, firstSeen map[string]int) (importPlan, bool) {
+	values := row.values
+	sku := values["sku"]
+	result.Row = row.number
+	result.SKU = sku
+
+	addError := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+	addWarning := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case sku == "":
+		addError("sku is required")
+	case len(sku) > 100:
+		addError("sku must be at most 100 characters")
+	default:
+		if first, ok := firstSeen[sku]; ok {
+			addError("duplicate SKU, first seen on row %d", first)
+		} else {
+			firstSeen[sku] = row.number
+		}
+	}
+
+	name := values["name"]
+	if len(name) > 255 {
+		addError("name must be at most 255 characters")
+	}
+
+	var price *float64
+	if value := values["price"]; value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		switch {
+		case err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0):
+			addError("price %q is not a number", value)
+		case parsed < 0:
+			addError("price must not be negative")
+		case parsed > maxProductPrice:
+			addError("price must be at most %.2f", maxProductPrice)
+		default:
+			price = &parsed
+		}
+	}
+
+	var quantity *int
+	if value := values["quantity"]; value != "" {
+		parsed, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			addError("quantity %q is not a whole number", value)
+		case parsed < 0:
+			addError("quantity must not be negative")
+		default:
+			quantity = &parsed
+		}
+	}
+
+	var categoryID *uint
+	if value := values["category"]; value != "" {
+		id, ok := categories[strings.ToLower(value)]
+		if !ok {
+			addError("unknown category %q", value)
+		} else {
+			categoryID = &id
+		}
+	}
+
+	status := strings.ToLower(values["status"])
+	product, exists := existing[sku]
+
+	if !exists {
+		if name == "" {
+			addError("name is required for new products")
+		}
+		if price == nil && values["price"] == "" {
+			addError("price is required for new products")
+		} else if price != nil && *price <= 0 {
+			addError("price must be greater than 0 for new products")
+		}
+		if status != "" && status != string(models.ProductStatusDraft) && status != string(models.ProductStatusActive) {
+			addError("status must be draft or active for new products")
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		result.Action = "invalid"
+		return importPlan{}, false
+	}
+
+	if !exists {
+		created := &models.Product{
+			Name:        name,
+			SKU:         sku,
+			Description: values["description"],
+			Price:       *price,
+			Status:      models.ProductStatusActive,
+			CategoryID:  categoryID,
+		}
+		if quantity != nil {
+			created.Quantity = *quantity
+		}
+		if status != "" {
+			created.Status = models.ProductStatus(status)
+		}
+		result.Action = "create"
+		return importPlan{result: result, create: created}, true
+	}
+
+	// Stock and lifecycle have their own endpoints and are not changed by imports
+	if quantity != nil && *quantity != product.Quantity {
+		addWarning("quantity is not changed for existing products, record a transaction instead")
+	}
+	if status != "" && status != string(product.Status) {
+		addWarning("status is not changed for existing products, use the status endpoint instead")
+	}
+
+	id := product.ID
+	result.ProductID = &id
+	previous := *product
+	updated := *product
+	if name != "" {
+		updated.Name = name
+	}
+	if description := values["description"]; description != "" {
+		updated.Description = description
+	}
+	if price != nil {
+		updated.Price = *price
+	}
+	if categoryID != nil {
+		updated.CategoryID = categoryID
+	}
+
+	if updated.Name == previous.Name && updated.Description == previous.Description &&
+		updated.Price == previous.Price && equalIDs(updated.CategoryID, previous.CategoryID) {
+		result.Action = "unchanged"
+		return importPlan{}, false
+	}
+
+	result.Action = "update"
+	return importPlan{result: result, change: &repo.ProductChange{Product: &updated, Previous: &previous}}, true
+}
+
+// importWriteError explains why a chunk could not be written
+func importWriteError(err error) string {
+	switch {
+	case errors.Is(err, repo.ErrVersionConflict):
+		return "a product in this chunk was modified during the import, nothing in the chunk was saved"
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return "a SKU in this chunk was created by another request during the import, nothing in the chunk was saved"
+	}
+	return fmt.Sprintf("failed to save chunk: %v", err)
+}
+
+func equalIDs(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -1,50 +1,73 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"inventory-api/dtos"
 	"inventory-api/models"
 	"inventory-api/repo"
+	"math"
+	"sort"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type InventoryService struct {
-	repo *repo.InventoryRepository
+	repo  *repo.InventoryRepository
+	audit *AuditService
 }
 
-func NewInventoryService(repo *repo.InventoryRepository) *InventoryService {
-	return &InventoryService{repo: repo}
+func NewInventoryService(repo *repo.InventoryRepository, audit *AuditService) *InventoryService {
+	return &InventoryService{repo: repo, audit: audit}
 }
 
 // Product services
-func (s *InventoryService) CreateProduct(input *dtos.CreateProductInput) (*dtos.ProductResponse, error) {
+func (s *InventoryService) CreateProduct(input *dtos.CreateProductInput, actor Actor) (*dtos.ProductResponse, error) {
 	// Check if SKU already exists
 	existing, err := s.repo.GetProductBySKU(input.SKU)
 	if err == nil && existing != nil {
 		return nil, errors.New("product with this SKU already exists")
 	}
 
+	if err := s.checkCategory(input.CategoryID); err != nil {
+		return nil, err
+	}
+
 	// Convert DTO to model
 	product := input.ToProductModel()
 
-	if err := s.repo.CreateProduct(product); err != nil {
+	var response *dtos.ProductResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).CreateProduct(product, actor.UserID); err != nil {
+			return err
+		}
+
+		// Convert model to response DTO
+		response = dtos.ToProductResponse(product)
+		audit.Record(AuditEntityProduct, product.ID, AuditActionCreate, nil, response)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	// Convert model to response DTO
-	return dtos.ToProductResponse(product), nil
+	return response, nil
 }
 
-func (s *InventoryService) GetProductByID(id uint) (*dtos.ProductResponse, error) {
-	product, err := s.repo.GetProductByID(id)
+// GetProductByID retrieves a product with only the projected fields
+func (s *InventoryService) GetProductByID(id uint, projection dtos.Projection) (*dtos.ProductResponse, error) {
+	product, err := s.repo.GetProductByIDWithFields(id, projection)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("product not found")
 		}
 		return nil, err
 	}
-	return dtos.ToProductResponse(product), nil
+	response := dtos.ToProductResponse(product)
+	response.SelectFields(projection.Fields)
+	return response, nil
 }
 
 func (s *InventoryService) GetProductBySKU(sku string) (*dtos.ProductResponse, error) {
@@ -74,24 +97,42 @@ func (s *InventoryService) GetAllProducts(limit, offset int) ([]dtos.ProductResp
 	return dtos.ToProductResponseList(products), nil
 }
 
-// GetProductsWithFilter retrieves products with filtering
-func (s *InventoryService) GetProductsWithFilter(filter *dtos.ProductFilter, limit, offset int) ([]dtos.ProductResponse, error) {
-	if limit <= 0 {
-		limit = 10
+// GetProductsWithFilter retrieves a page of products with filtering
+func (s *InventoryService) GetProductsWithFilter(filter *dtos.ProductFilter, sort []dtos.SortField, page dtos.PageRequest, projection dtos.Projection) ([]dtos.ProductResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
 	}
-	if offset < 0 {
-		offset = 0
+
+	// Search results are ranked by relevance, which has no stable cursor position
+	if filter.HasQuery() {
+		if page.Cursor != "" && len(sort) == 0 {
+			return nil, dtos.PageInfo{}, ErrCursorNotSupported
+		}
+		results, info, err := s.repo.SearchProducts(filter, sort, page, projection)
+		if err != nil {
+			return nil, dtos.PageInfo{}, pageError(err)
+		}
+		if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountProductsWithFilter(filter) }); err != nil {
+			return nil, dtos.PageInfo{}, err
+		}
+		return selectProductFields(dtos.ToProductSearchResponseList(results), projection), info, nil
 	}
 
-	products, err := s.repo.GetProductsWithFilter(filter, limit, offset)
+	products, info, err := s.repo.GetProductsWithFilter(filter, sort, page, projection)
 	if err != nil {
-		return nil, err
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountProductsWithFilter(filter) }); err != nil {
+		return nil, dtos.PageInfo{}, err
 	}
 
-	return dtos.ToProductResponseList(products), nil
+	return selectProductFields(dtos.ToProductResponseList(products), projection), info, nil
 }
 
-func (s *InventoryService) UpdateProduct(id uint, input *dtos.UpdateProductInput) (*dtos.ProductResponse, error) {
+// UpdateProduct applies input to the product. When expectedVersion is non-zero the
+// update only succeeds if the product is still at that version.
+func (s *InventoryService) UpdateProduct(id uint, input *dtos.UpdateProductInput, expectedVersion uint, actor Actor) (*dtos.ProductResponse, error) {
 	product, err := s.repo.GetProductByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -100,19 +141,230 @@ func (s *InventoryService) UpdateProduct(id uint, input *dtos.UpdateProductInput
 		return nil, err
 	}
 
+	if expectedVersion != 0 && product.Version != expectedVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	if err := s.checkCategory(input.CategoryID); err != nil {
+		return nil, err
+	}
+
+	previous := *product
+
 	// Apply DTO updates to model
 	input.ApplyToProduct(product)
 
-	if err := s.repo.UpdateProduct(product); err != nil {
+	var response *dtos.ProductResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if _, err := s.repo.WithTx(tx).UpdateProductWithRevision(product, &previous, expectedVersion, actor.UserID); err != nil {
+			return err
+		}
+		response = dtos.ToProductResponse(product)
+		audit.Record(AuditEntityProduct, product.ID, AuditActionUpdate, dtos.ToProductResponse(&previous), response)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return nil, ErrVersionMismatch
+		}
 		return nil, err
 	}
+	return response, nil
+}
 
-	return dtos.ToProductResponse(product), nil
+// Category services
+func (s *InventoryService) CreateCategory(input *dtos.CreateCategoryInput, actor Actor) (*dtos.CategoryResponse, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("category name is required")
+	}
+	if existing, err := s.repo.GetCategoryByName(name); err == nil && existing != nil {
+		return nil, errors.New("category with this name already exists")
+	}
+
+	category := &models.Category{Name: name}
+	var response dtos.CategoryResponse
+	err := s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).CreateCategory(category); err != nil {
+			return err
+		}
+		response = dtos.ToCategoryResponse(category)
+		audit.Record(AuditEntityCategory, category.ID, AuditActionCreate, nil, response)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, errors.New("category with this name already exists")
+		}
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (s *InventoryService) GetCategories() ([]dtos.CategoryResponse, error) {
+	categories, err := s.repo.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	return dtos.ToCategoryResponseList(categories), nil
 }
 
-func (s *InventoryService) DeleteProduct(id uint) error {
+// checkCategory verifies that an optional category reference exists
+func (s *InventoryService) checkCategory(categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	if _, err := s.repo.GetCategoryByID(*categoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetProductRevisions lists the stored revisions of a product, newest first
+func (s *InventoryService) GetProductRevisions(productID uint, page dtos.PageRequest) ([]dtos.ProductRevisionResponse, dtos.PageInfo, error) {
+	if _, err := s.repo.GetProductByID(productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, dtos.PageInfo{}, errors.New("product not found")
+		}
+		return nil, dtos.PageInfo{}, err
+	}
+
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	revisions, info, err := s.repo.GetProductRevisions(productID, page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountProductRevisions(productID) }); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	return dtos.ToProductRevisionResponseList(revisions), info, nil
+}
+
+// GetProductRevisionDiff compares revision n with the revision before it. The first
+// revision is compared with an empty product.
+func (s *InventoryService) GetProductRevisionDiff(productID, n uint) (*dtos.ProductRevisionDiff, error) {
+	revision, err := s.getProductRevision(productID, n)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &dtos.ProductRevisionDiff{Revision: n, Changes: []dtos.FieldChange{}}
+	before := []byte("{}")
+	if n > 1 {
+		previous, err := s.getProductRevision(productID, n-1)
+		if err != nil {
+			return nil, err
+		}
+		previousNumber := previous.Revision
+		diff.PreviousRevision = &previousNumber
+		if before, err = json.Marshal(dtos.ToProductRevisionResponse(previous).ProductRevisionFields); err != nil {
+			return nil, err
+		}
+	}
+	after, err := json.Marshal(dtos.ToProductRevisionResponse(revision).ProductRevisionFields)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffFields(before, after)
+	if err != nil {
+		return nil, err
+	}
+	for field, change := range changes {
+		diff.Changes = append(diff.Changes, dtos.FieldChange{Field: field, From: change.From, To: change.To})
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Field < diff.Changes[j].Field })
+
+	return diff, nil
+}
+
+// RollbackProduct restores the name, description and price of revision n through
+// the regular update, which records the result as a new revision
+func (s *InventoryService) RollbackProduct(productID, n, expectedVersion uint, actor Actor) (*dtos.ProductResponse, error) {
+	revision, err := s.getProductRevision(productID, n)
+	if err != nil {
+		return nil, err
+	}
+	return s.UpdateProduct(productID, dtos.ToRollbackInput(revision), expectedVersion, actor)
+}
+
+func (s *InventoryService) getProductRevision(productID, n uint) (*models.ProductRevision, error) {
+	revision, err := s.repo.GetProductRevision(productID, n)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ChangeProductStatus moves a product through its lifecycle and records who changed it.
+// When expectedVersion is non-zero the change only succeeds if the product is still
+// at that version; otherwise only the status columns are written, so concurrent
+// stock movements cannot make it fail.
+func (s *InventoryService) ChangeProductStatus(id uint, status string, expectedVersion uint, actor Actor) (*dtos.ProductResponse, error) {
+	product, err := s.repo.GetProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	if expectedVersion != 0 && product.Version != expectedVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	newStatus := models.ProductStatus(status)
+	if !models.IsValidProductStatus(newStatus) {
+		return nil, errors.New("invalid product status")
+	}
+	if !models.CanTransitionProductStatus(product.Status, newStatus) {
+		return nil, fmt.Errorf("cannot change product status from %s to %s", product.Status, newStatus)
+	}
+
+	previous := *product
+
+	now := time.Now()
+	changedBy := actor.UserID
+	product.Status = newStatus
+	product.StatusChangedAt = &now
+	product.StatusChangedBy = &changedBy
+
+	var response *dtos.ProductResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).UpdateProduct(product, &previous, expectedVersion); err != nil {
+			return err
+		}
+		// previous now holds the locked row, which another request may have moved on
+		if !models.CanTransitionProductStatus(previous.Status, newStatus) {
+			return fmt.Errorf("cannot change product status from %s to %s", previous.Status, newStatus)
+		}
+		response = dtos.ToProductResponse(product)
+		audit.Record(AuditEntityProduct, product.ID, AuditActionStatusChange, dtos.ToProductResponse(&previous), response)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return nil, ErrVersionMismatch
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *InventoryService) DeleteProduct(id uint, actor Actor) error {
 	// Check if product exists
-	_, err := s.repo.GetProductByID(id)
+	product, err := s.repo.GetProductByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("product not found")
@@ -120,13 +372,387 @@ func (s *InventoryService) DeleteProduct(id uint) error {
 		return err
 	}
 
-	return s.repo.DeleteProduct(id)
+	return s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).DeleteProduct(id); err != nil {
+			return err
+		}
+		audit.Record(AuditEntityProduct, id, AuditActionDelete, dtos.ToProductResponse(product), nil)
+		return nil
+	})
+}
+
+// maxBulkProducts caps how many products one bulk operation may change
+const maxBulkProducts = 10000
+
+// BulkUpdateProducts applies one operation to the products given by ID or filter.
+// Products the operation cannot apply to are reported as invalid and skipped; the
+// rest are written in one transaction and recorded as a single audit entry. In a
+// dry run nothing is written.
+func (s *InventoryService) BulkUpdateProducts(input *dtos.BulkProductInput, dryRun bool, actor Actor) (*dtos.BulkProductReport, error) {
+	if err := validateBulkInput(input); err != nil {
+		return nil, err
+	}
+	if input.Operation == dtos.BulkOperationSetCategory {
+		if err := s.checkCategory(input.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	products, missing, err := s.bulkTargets(input)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dtos.BulkProductReport{
+		DryRun:    dryRun,
+		Operation: input.Operation,
+		Products:  make([]dtos.BulkProductResult, 0, len(products)+len(missing)),
+	}
+	var updates []repo.ProductChange
+	var deletes []models.Product
+	var updated []int
+	for i := range products {
+		product := &products[i]
+		result := dtos.BulkProductResult{ProductID: product.ID, SKU: product.SKU, Before: dtos.ToProductResponse(product)}
+
+		if input.Operation == dtos.BulkOperationDelete {
+			result.Action = "delete"
+			deletes = append(deletes, *product)
+			report.Products = append(report.Products, result)
+			continue
+		}
+
+		previous := *product
+		changed, errs := applyBulkOperation(input, product, actor)
+		switch {
+		case len(errs) > 0:
+			result.Action = "invalid"
+			result.Errors = errs
+		case !changed:
+			result.Action = "unchanged"
+		default:
+			result.Action = "update"
+			result.After = dtos.ToProductResponse(product)
+			updates = append(updates, repo.ProductChange{Product: product, Previous: &previous})
+			updated = append(updated, len(report.Products))
+		}
+		report.Products = append(report.Products, result)
+	}
+	for _, id := range missing {
+		report.Products = append(report.Products, dtos.BulkProductResult{
+			ProductID: id,
+			Action:    "invalid",
+			Errors:    []string{"product not found"},
+		})
+	}
+
+	if !dryRun {
+		if err := s.applyBulkChanges(updates, deletes, actor); err != nil {
+			return nil, err
+		}
+		// Show the saved state, including the new versions
+		for i, index := range updated {
+			report.Products[index].After = dtos.ToProductResponse(updates[i].Product)
+		}
+	}
+
+	report.Matched = len(products)
+	for _, result := range report.Products {
+		switch result.Action {
+		case "update":
+			report.Updated++
+		case "delete":
+			report.Deleted++
+		case "unchanged":
+			report.Unchanged++
+		case "invalid":
+			report.Invalid++
+		}
+	}
+	return report, nil
+}
+
+// bulkTargets loads the products a bulk operation applies to, along with any
+// requested IDs that do not exist
+func (s *InventoryService) bulkTargets(input *dtos.BulkProductInput) ([]models.Product, []uint, error) {
+	if len(input.IDs) == 0 {
+		products, err := s.repo.GetProductsMatching(input.Filter, maxBulkProducts+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(products) > maxBulkProducts {
+			return nil, nil, fmt.Errorf("%w (at most %d)", ErrBulkTooManyProducts, maxBulkProducts)
+		}
+		return products, nil, nil
+	}
+
+	products, err := s.repo.GetProductsByIDs(input.IDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	found := make(map[uint]bool, len(products))
+	for _, product := range products {
+		found[product.ID] = true
+	}
+	var missing []uint
+	for _, id := range input.IDs {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true
+		}
+	}
+	return products, missing, nil
+}
+
+// applyBulkChanges writes a bulk operation and records it as one audit entry
+// holding every affected product before and after
+func (s *InventoryService) applyBulkChanges(updates []repo.ProductChange, deletes []models.Product, actor Actor) error {
+	if len(deletes) == 0 && len(updates) == 0 {
+		return nil
+	}
+
+	err := s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if len(deletes) > 0 {
+			if err := s.repo.WithTx(tx).BulkDeleteProducts(deletes); err != nil {
+				return err
+			}
+
+			before := make(map[uint]*dtos.ProductResponse, len(deletes))
+			for i := range deletes {
+				before[deletes[i].ID] = dtos.ToProductResponse(&deletes[i])
+			}
+			audit.Record(AuditEntityProduct, 0, AuditActionBulkDelete, before, nil)
+			return nil
+		}
+
+		if err := s.repo.WithTx(tx).BulkUpdateProducts(updates, actor.UserID); err != nil {
+			return err
+		}
+
+		before := make(map[uint]*dtos.ProductResponse, len(updates))
+		after := make(map[uint]*dtos.ProductResponse, len(updates))
+		for _, change := range updates {
+			before[change.Product.ID] = dtos.ToProductResponse(change.Previous)
+			after[change.Product.ID] = dtos.ToProductResponse(change.Product)
+		}
+		audit.Record(AuditEntityProduct, 0, AuditActionBulkUpdate, before, after)
+		return nil
+	})
+	if errors.Is(err, repo.ErrVersionConflict) {
+		return ErrVersionMismatch
+	}
+	return err
+}
+
+// validateBulkInput checks that a bulk operation has a target and the parameters
+// its operation needs
+func validateBulkInput(input *dtos.BulkProductInput) error {
+	if (len(input.IDs) > 0) == !input.Filter.IsEmpty() {
+		return ErrBulkTarget
+	}
+	if input.Filter != nil && input.Filter.IncludeDeleted {
+		return fmt.Errorf("%w: deleted products cannot be changed", ErrInvalidBulkOperation)
+	}
+
+	switch input.Operation {
+	case dtos.BulkOperationSetPrice:
+		if input.Price == nil {
+			return fmt.Errorf("%w: price is required for set_price", ErrInvalidBulkOperation)
+		}
+	case dtos.BulkOperationAdjustPrice:
+		if (input.Percent == nil) == (input.Amount == nil) {
+			return fmt.Errorf("%w: give either percent or amount for adjust_price", ErrInvalidBulkOperation)
+		}
+	case dtos.BulkOperationSetStatus:
+		if !models.IsValidProductStatus(models.ProductStatus(input.Status)) {
+			return fmt.Errorf("%w: a valid status is required for set_status", ErrInvalidBulkOperation)
+		}
+	case dtos.BulkOperationSetCategory, dtos.BulkOperationDelete:
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidBulkOperation, input.Operation)
+	}
+	return nil
+}
+
+// applyBulkOperation applies a non-delete bulk operation to product and reports
+// whether it changed, or why the operation does not apply to it
+func applyBulkOperation(input *dtos.BulkProductInput, product *models.Product, actor Actor) (bool, []string) {
+	switch input.Operation {
+	case dtos.BulkOperationSetPrice, dtos.BulkOperationAdjustPrice:
+		var price float64
+		switch {
+		case input.Operation == dtos.BulkOperationSetPrice:
+			price = *input.Price
+		case input.Percent != nil:
+			price = product.Price * (1 + *input.Percent/100)
+		default:
+			price = product.Price + *input.Amount
+		}
+		price = roundPrice(price, input.Rounding)
+
+		switch {
+		case price < 0:
+			return false, []string{fmt.Sprintf("new price %.2f would be negative", price)}
+		case price > maxProductPrice:
+			return false, []string{fmt.Sprintf("new price %.2f would exceed %.2f", price, maxProductPrice)}
+		case price == product.Price:
+			return false, nil
+		}
+		product.Price = price
+	case dtos.BulkOperationSetStatus:
+		status := models.ProductStatus(input.Status)
+		if status == product.Status {
+			return false, nil
+		}
+		if !models.CanTransitionProductStatus(product.Status, status) {
+			return false, []string{fmt.Sprintf("cannot change product status from %s to %s", product.Status, status)}
+		}
+		now := time.Now()
+		changedBy := actor.UserID
+		product.Status = status
+		product.StatusChangedAt = &now
+		product.StatusChangedBy = &changedBy
+	case dtos.BulkOperationSetCategory:
+		if equalIDs(product.CategoryID, input.CategoryID) {
+			return false, nil
+		}
+		product.CategoryID = input.CategoryID
+	}
+	return true, nil
+}
+
+// roundPrice rounds a computed price by the given rounding rule, always to whole cents
+func roundPrice(price float64, rounding string) float64 {
+	switch rounding {
+	case dtos.PriceRoundingWhole:
+		price = math.Round(price)
+	case dtos.PriceRoundingEnding99:
+		// Nearest price ending in .99, the lowest being 0.99
+		if price > 0 {
+			price = max(math.Round(price+0.01)-0.01, 0.99)
+		}
+	}
+	return math.Round(price*100) / 100
+}
+
+// Trash services for soft-deleted products
+func (s *InventoryService) GetDeletedProducts(page dtos.PageRequest) ([]dtos.ProductResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	products, info, err := s.repo.GetDeletedProducts(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, s.repo.CountDeletedProducts); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	return dtos.ToProductResponseList(products), info, nil
+}
+
+// RestoreProduct brings a soft-deleted product back, unless its SKU has been reused
+func (s *InventoryService) RestoreProduct(id uint, actor Actor) (*dtos.ProductResponse, error) {
+	product, err := s.repo.GetDeletedProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("deleted product not found")
+		}
+		return nil, err
+	}
+
+	// The SKU index only covers active products, so it may have been reused
+	existing, err := s.repo.GetProductBySKU(product.SKU)
+	if err == nil && existing != nil {
+		return nil, ErrSKUConflict
+	}
+
+	var response *dtos.ProductResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		txRepo := s.repo.WithTx(tx)
+		if err := txRepo.RestoreProduct(id); err != nil {
+			return err
+		}
+
+		restored, err := txRepo.GetProductByID(id)
+		if err != nil {
+			return err
+		}
+
+		response = dtos.ToProductResponse(restored)
+		audit.Record(AuditEntityProduct, id, AuditActionRestore, dtos.ToProductResponse(product), response)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrSKUConflict
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// PurgeProduct permanently deletes a soft-deleted product without IN or OUT transactions
+func (s *InventoryService) PurgeProduct(id uint, actor Actor) error {
+	product, err := s.repo.GetDeletedProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("deleted product not found")
+		}
+		return err
+	}
+
+	count, err := s.repo.CountMovementsByProductID(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrProductHasTransactions
+	}
+
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).PurgeProduct(id); err != nil {
+			return err
+		}
+		audit.Record(AuditEntityProduct, id, AuditActionPurge, dtos.ToProductResponse(product), nil)
+		return nil
+	})
+	if errors.Is(err, repo.ErrProductHasMovements) {
+		return ErrProductHasTransactions
+	}
+	return err
+}
+
+// PurgeDeletedProducts permanently deletes every soft-deleted product without IN or OUT transactions
+func (s *InventoryService) PurgeDeletedProducts(actor Actor) (int64, error) {
+	var purged []models.Product
+	err := s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		var err error
+		purged, err = s.repo.WithTx(tx).PurgeDeletedProducts()
+		if err != nil {
+			return err
+		}
+
+		changes := make([]AuditChange, len(purged))
+		for i := range purged {
+			changes[i] = AuditChange{EntityID: purged[i].ID, Before: dtos.ToProductResponse(&purged[i])}
+		}
+		audit.RecordAll(AuditEntityProduct, AuditActionPurge, changes)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(purged)), nil
 }
 
 // Transaction services
-func (s *InventoryService) CreateTransaction(input *dtos.CreateTransactionInput) (*dtos.TransactionResponse, error) {
+// CreateTransaction records a stock movement made by the given user
+func (s *InventoryService) CreateTransaction(input *dtos.CreateTransactionInput, actor Actor) (*dtos.TransactionResponse, error) {
 	// Validate product exists
-	_, err := s.repo.GetProductByID(input.ProductID)
+	product, err := s.repo.GetProductByID(input.ProductID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("product not found")
@@ -140,62 +766,140 @@ func (s *InventoryService) CreateTransaction(input *dtos.CreateTransactionInput)
 	}
 
 	// Update product quantity with transaction
-	err = s.repo.UpdateProductQuantityWithTransaction(
-		input.ProductID,
-		input.Quantity,
-		models.TransactionType(input.TransactionType),
-		input.Notes,
-	)
+	var response *dtos.TransactionResponse
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		transaction, err := s.repo.WithTx(tx).UpdateProductQuantityWithTransaction(
+			input.ProductID,
+			input.Quantity,
+			models.TransactionType(input.TransactionType),
+			input.Notes,
+			actor.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		response = dtos.ToTransactionResponse(transaction)
+		audit.Record(AuditEntityTransaction, transaction.ID, AuditActionCreate, nil, transactionSnapshot(*response))
+		return nil
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrInvalidData) {
 			return nil, errors.New("insufficient quantity for OUT transaction")
 		}
+		if errors.Is(err, repo.ErrMovementNotAllowed) {
+			return nil, fmt.Errorf("product is %s and does not accept %s movements", product.Status, input.TransactionType)
+		}
 		return nil, err
 	}
+	return response, nil
+}
+
+// CreateTransactionBatch applies all lines of a document atomically on behalf of the given user
+func (s *InventoryService) CreateTransactionBatch(input *dtos.CreateTransactionBatchInput, actor Actor) (*dtos.TransactionBatchResponse, error) {
+	if len(input.Lines) == 0 {
+		return nil, errors.New("batch must contain at least one line")
+	}
+
+	// Validate quantities before touching the database
+	var lineErrors []dtos.BatchLineError
+	transactions := make([]models.Transaction, len(input.Lines))
+	for i, line := range input.Lines {
+		if line.Quantity <= 0 {
+			lineErrors = append(lineErrors, dtos.BatchLineError{
+				Line:    i,
+				Field:   "quantity",
+				Message: "quantity must be greater than 0",
+				Value:   line.Quantity,
+			})
+		}
+		transactions[i] = *line.ToTransactionModel()
+		transactions[i].Reference = input.Reference
+		transactions[i].CreatedBy = &actor.UserID
+	}
+	if len(lineErrors) > 0 {
+		return nil, &dtos.BatchValidationError{Errors: lineErrors}
+	}
+
+	var responses []dtos.TransactionResponse
+	err := s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.repo.WithTx(tx).CreateTransactionBatch(transactions); err != nil {
+			return err
+		}
 
-	// Get the created transaction (last one for this product)
-	transactions, err := s.repo.GetTransactionsByProductID(input.ProductID, 1, 0)
-	if err != nil || len(transactions) == 0 {
-		return nil, errors.New("failed to retrieve created transaction")
+		responses = dtos.ToTransactionResponseList(transactions)
+		changes := make([]AuditChange, len(responses))
+		for i, response := range responses {
+			changes[i] = AuditChange{EntityID: response.ID, After: transactionSnapshot(response)}
+		}
+		audit.RecordAll(AuditEntityTransaction, AuditActionCreate, changes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return dtos.ToTransactionResponse(&transactions[0]), nil
+	return &dtos.TransactionBatchResponse{
+		Reference:    input.Reference,
+		Transactions: responses,
+	}, nil
 }
 
-func (s *InventoryService) GetTransactionByID(id uint) (*dtos.TransactionResponse, error) {
-	transaction, err := s.repo.GetTransactionByID(id)
+// GetTransactionByID retrieves a transaction with only the projected fields and relations
+func (s *InventoryService) GetTransactionByID(id uint, projection dtos.Projection) (*dtos.TransactionResponse, error) {
+	transaction, err := s.repo.GetTransactionByID(id, projection)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("transaction not found")
 		}
 		return nil, err
 	}
-	return dtos.ToTransactionResponse(transaction), nil
+	response := dtos.ToTransactionResponse(transaction)
+	response.SelectFields(projection.Fields)
+	return response, nil
 }
 
-func (s *InventoryService) GetTransactionsByProductID(productID uint, limit, offset int) ([]dtos.TransactionResponse, error) {
-	if limit <= 0 {
-		limit = 10
-	}
-	if offset < 0 {
-		offset = 0
+func (s *InventoryService) GetTransactionsByProductID(productID uint, page dtos.PageRequest, projection dtos.Projection) ([]dtos.TransactionResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
 	}
 
-	// Validate product exists
-	_, err := s.repo.GetProductByID(productID)
+	// Validate product exists, deleted products still have a history
+	_, err = s.repo.GetProductByIDUnscoped(productID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, dtos.PageInfo{}, errors.New("product not found")
 		}
-		return nil, err
+		return nil, dtos.PageInfo{}, err
 	}
 
-	transactions, err := s.repo.GetTransactionsByProductID(productID, limit, offset)
+	transactions, info, err := s.repo.GetTransactionsByProductID(productID, page, projection)
 	if err != nil {
-		return nil, err
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountMovementsByProductID(productID) }); err != nil {
+		return nil, dtos.PageInfo{}, err
 	}
 
-	return dtos.ToTransactionResponseList(transactions), nil
+	return selectTransactionFields(dtos.ToTransactionResponseList(transactions), projection), info, nil
+}
+
+// GetTransactionsWithFilter retrieves a page of transactions with filtering and sorting
+func (s *InventoryService) GetTransactionsWithFilter(filter *dtos.TransactionFilter, sort []dtos.SortField, page dtos.PageRequest, projection dtos.Projection) ([]dtos.TransactionResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	transactions, info, err := s.repo.GetTransactionsWithFilter(filter, sort, page, projection)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountTransactionsWithFilter(filter) }); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	return selectTransactionFields(dtos.ToTransactionResponseList(transactions), projection), info, nil
 }
 
 func (s *InventoryService) GetAllTransactions(limit, offset int) ([]dtos.TransactionResponse, error) {
@@ -213,3 +917,26 @@ func (s *InventoryService) GetAllTransactions(limit, offset int) ([]dtos.Transac
 
 	return dtos.ToTransactionResponseList(transactions), nil
 }
+
+// selectProductFields limits each response to the projected fields
+// transactionSnapshot drops the nested product and user, which are audited on their own
+func transactionSnapshot(transaction dtos.TransactionResponse) dtos.TransactionResponse {
+	transaction.Product = nil
+	transaction.User = nil
+	return transaction
+}
+
+func selectProductFields(products []dtos.ProductResponse, projection dtos.Projection) []dtos.ProductResponse {
+	for i := range products {
+		products[i].SelectFields(projection.Fields)
+	}
+	return products
+}
+
+// selectTransactionFields limits each response to the projected fields
+func selectTransactionFields(transactions []dtos.TransactionResponse, projection dtos.Projection) []dtos.TransactionResponse {
+	for i := range transactions {
+		transactions[i].SelectFields(projection.Fields)
+	}
+	return transactions
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"testing"
+)
+
+// TestApplyBulkOperationPrice covers every price operation. adjust_price never
+// carries a price, so its cases also guard against reading the unset one, which
+// used to panic.
+func TestApplyBulkOperationPrice(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		input   dtos.BulkProductInput
+		want    float64
+		changed bool
+	}{
+		{
+			name:    "set price",
+			input:   dtos.BulkProductInput{Operation: dtos.BulkOperationSetPrice, Price: price(15), Rounding: dtos.PriceRoundingCents},
+			want:    15,
+			changed: true,
+		},
+		{
+			name:    "adjust price by percent",
+			input:   dtos.BulkProductInput{Operation: dtos.BulkOperationAdjustPrice, Percent: price(10), Rounding: dtos.PriceRoundingCents},
+			want:    11,
+			changed: true,
+		},
+		{
+			name:    "adjust price by amount",
+			input:   dtos.BulkProductInput{Operation: dtos.BulkOperationAdjustPrice, Amount: price(-2.5), Rounding: dtos.PriceRoundingCents},
+			want:    7.5,
+			changed: true,
+		},
+		{
+			name:    "adjust price with rounding to .99",
+			input:   dtos.BulkProductInput{Operation: dtos.BulkOperationAdjustPrice, Percent: price(25), Rounding: dtos.PriceRoundingEnding99},
+			want:    12.99,
+			changed: true,
+		},
+		{
+			name:    "set price unchanged",
+			input:   dtos.BulkProductInput{Operation: dtos.BulkOperationSetPrice, Price: price(10), Rounding: dtos.PriceRoundingCents},
+			want:    10,
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.IDs = []uint{1}
+			if tt.input.Operation == dtos.BulkOperationAdjustPrice && tt.input.Price != nil {
+				t.Fatal("adjust_price cases must leave the price unset")
+			}
+			if err := validateBulkInput(&tt.input); err != nil {
+				t.Fatalf("validateBulkInput() error = %v", err)
+			}
+
+			product := &models.Product{ID: 1, Price: 10}
+			changed, errs := applyBulkOperation(&tt.input, product, Actor{UserID: 1})
+			if len(errs) > 0 {
+				t.Fatalf("applyBulkOperation() errors = %v", errs)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if product.Price != tt.want {
+				t.Errorf("price = %v, want %v", product.Price, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyBulkOperationNegativePrice(t *testing.T) {
+	amount := -20.0
+	input := &dtos.BulkProductInput{Operation: dtos.BulkOperationAdjustPrice, Amount: &amount, IDs: []uint{1}}
+	product := &models.Product{ID: 1, Price: 10}
+
+	changed, errs := applyBulkOperation(input, product, Actor{})
+	if changed || len(errs) != 1 {
+		t.Fatalf("applyBulkOperation() = %v, %v, want an error", changed, errs)
+	}
+	if product.Price != 10 {
+		t.Errorf("price = %v, want it left at 10", product.Price)
+	}
+}
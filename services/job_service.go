@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"inventory-api/storage"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// jobLease is how long a claimed job stays locked to its worker without a
+// heartbeat; workers renew it every third of that
+const jobLease = time.Minute
+
+// jobProgressInterval limits how often progress is written to the database
+const jobProgressInterval = time.Second
+
+var (
+	ErrJobNotFound        = errors.New("job not found")
+	ErrJobCancelled       = errors.New("job cancelled")
+	ErrJobFinished        = errors.New("job has already finished")
+	ErrJobResultNotReady  = errors.New("job has no result to download")
+	ErrUnknownJobType     = errors.New("unknown job type")
+	ErrInvalidJobParams   = errors.New("invalid job parameters")
+	errJobAbandoned       = errors.New("job was abandoned by its worker too many times")
+	errJobHandlerPanicked = errors.New("job handler panicked")
+)
+
+// nonRetryableError marks a job error that retrying cannot fix
+type nonRetryableError struct {
+	err error
+}
+
+func (e nonRetryableError) Error() string { return e.err.Error() }
+func (e nonRetryableError) Unwrap() error { return e.err }
+
+// NonRetryable marks err so the job fails right away instead of being retried,
+// for example when its input is invalid
+func NonRetryable(err error) error {
+	return nonRetryableError{err: err}
+}
+
+// ProgressFunc reports that done of total units of work are complete. Long
+// running work should call it regularly and stop when it returns an error,
+// which happens once the job it runs in is cancelled.
+type ProgressFunc func(done, total int) error
+
+// JobFunc does the work of one job type
+type JobFunc func(run *JobRun) error
+
+// JobService queues background jobs and runs them on a pool of workers. Jobs are
+// stored in the database and claimed with row locks, so any number of API
+// replicas can run workers against the same queue.
+type JobService struct {
+	repo         *repo.JobRepository
+	storage      storage.Storage
+	handlers     map[string]JobFunc
+	workerID     string
+	workers      int
+	pollInterval time.Duration
+	maxAttempts  int
+}
+
+func NewJobService(repo *repo.JobRepository, storage storage.Storage, workers int, pollInterval time.Duration, maxAttempts int) *JobService {
+	host, _ := os.Hostname()
+	return &JobService{
+		repo:         repo,
+		storage:      storage,
+		handlers:     make(map[string]JobFunc),
+		workerID:     fmt.Sprintf("%s-%d-%s", host, os.Getpid(), randomName()[:8]),
+		workers:      workers,
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// Register makes a job type available. It must be called before Start.
+func (s *JobService) Register(jobType string, fn JobFunc) {
+	s.handlers[jobType] = fn
+}
+
+// Enqueue queues a job of the given type with params encoded as JSON
+func (s *JobService) Enqueue(jobType string, params interface{}, actor Actor) (*dtos.JobResponse, error) {
+	return s.enqueue(jobType, params, "", actor)
+}
+
+// EnqueueWithInput stores input, such as an uploaded file, and queues a job that
+// reads it with JobRun.OpenInput. The input is deleted once the job finishes.
+func (s *JobService) EnqueueWithInput(jobType string, params interface{}, input io.Reader, size int64, contentType string, actor Actor) (*dtos.JobResponse, error) {
+	key := "jobs/inputs/" + randomName()
+	if err := s.storage.Put(context.Background(), key, input, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store job input: %w", err)
+	}
+
+	job, err := s.enqueue(jobType, params, key, actor)
+	if err != nil {
+		s.deleteObject(key)
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *JobService) enqueue(jobType string, params interface{}, inputKey string, actor Actor) (*dtos.JobResponse, error) {
+	job, err := s.newJob(jobType, params, inputKey, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateJob(job); err != nil {
+		return nil, err
+	}
+
+	response := dtos.ToJobResponse(job)
+	return &response, nil
+}
+
+// ScheduleDaily queues a job of the given type every day at hour:00 UTC until
+// ctx is done. A run that came due while no replica was up is queued at
+// startup. Replicas share the schedule: a run is only queued when no other
+// scheduled job of the type was queued since it came due.
+func (s *JobService) ScheduleDaily(ctx context.Context, jobType string, params interface{}, hour int) {
+	go func() {
+		for {
+			now := time.Now().UTC()
+			due := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+			if due.After(now) {
+				due = due.AddDate(0, 0, -1)
+			}
+
+			wait := time.Until(due.AddDate(0, 0, 1))
+			if err := s.enqueueOnce(jobType, params, due); err != nil {
+				log.Printf("Failed to queue scheduled %s job: %v", jobType, err)
+				wait = min(wait, time.Minute)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// enqueueOnce queues a scheduled job unless another was queued since due
+func (s *JobService) enqueueOnce(jobType string, params interface{}, due time.Time) error {
+	job, err := s.newJob(jobType, params, "", Actor{})
+	if err != nil {
+		return err
+	}
+	created, err := s.repo.CreateScheduledJob(job, due)
+	if created {
+		log.Printf("Queued scheduled %s job %d", jobType, job.ID)
+	}
+	return err
+}
+
+func (s *JobService) newJob(jobType string, params interface{}, inputKey string, actor Actor) (*models.Job, error) {
+	if _, ok := s.handlers[jobType]; !ok {
+		return nil, ErrUnknownJobType
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Status:      models.JobStatusQueued,
+		Params:      string(data),
+		InputKey:    inputKey,
+		MaxAttempts: s.maxAttempts,
+		RunAt:       time.Now(),
+		CreatorName: actor.Username,
+		RequestID:   actor.RequestID,
+		IP:          actor.IP,
+	}
+	if actor.UserID != 0 {
+		job.CreatedBy = &actor.UserID
+	}
+	return job, nil
+}
+
+func (s *JobService) GetJob(id uint) (*dtos.JobResponse, error) {
+	job, err := s.getJob(id)
+	if err != nil {
+		return nil, err
+	}
+	response := dtos.ToJobResponse(job)
+	return &response, nil
+}
+
+// GetJobs retrieves a page of jobs, newest first
+func (s *JobService) GetJobs(filter *dtos.JobFilter, page dtos.PageRequest) ([]dtos.JobResponse, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	jobs, info, err := s.repo.GetJobs(filter, page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, func() (int64, error) { return s.repo.CountJobs(filter) }); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+	return dtos.ToJobResponseList(jobs), info, nil
+}
+
+// CancelJob cancels a queued job immediately. A running job is asked to stop
+// and is marked cancelled by its worker at the next progress report.
+func (s *JobService) CancelJob(id uint) (*dtos.JobResponse, error) {
+	job, err := s.getJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsFinished() {
+		return nil, ErrJobFinished
+	}
+
+	job, err = s.repo.CancelJob(id)
+	if err != nil {
+		return nil, err
+	}
+	response := dtos.ToJobResponse(job)
+	return &response, nil
+}
+
+// OpenJobResult opens the result file of a succeeded job
+func (s *JobService) OpenJobResult(id uint) (*FileDownload, error) {
+	job, err := s.getJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusSucceeded || job.ResultKey == "" {
+		return nil, ErrJobResultNotReady
+	}
+
+	content, err := s.storage.Get(context.Background(), job.ResultKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrJobResultNotReady
+		}
+		return nil, err
+	}
+	return &FileDownload{
+		FileName:    job.ResultName,
+		ContentType: job.ResultType,
+		Size:        job.ResultSize,
+		Content:     content,
+	}, nil
+}
+
+func (s *JobService) getJob(id uint) (*models.Job, error) {
+	job, err := s.repo.GetJobByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// Start launches the worker pool. Workers stop claiming new jobs once ctx is done.
+func (s *JobService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+}
+
+func (s *JobService) work(ctx context.Context) {
+	for {
+		job, err := s.repo.ClaimJob(s.workerID, jobLease)
+		if err != nil {
+			log.Println("Failed to claim job:", err)
+		}
+		if job != nil {
+			s.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// run executes a claimed job and records its outcome. Failed jobs are retried
+// with a growing delay until they run out of attempts.
+func (s *JobService) run(parent context.Context, job *models.Job) {
+	fn, ok := s.handlers[job.Type]
+	switch {
+	case job.CancelRequested:
+		s.finish(job, models.JobStatusCancelled, nil)
+		return
+	case !ok:
+		s.finish(job, models.JobStatusFailed, map[string]interface{}{"error": ErrUnknownJobType.Error()})
+		return
+	case job.Attempts > job.MaxAttempts:
+		// The previous worker died holding the job on its last attempt
+		s.finish(job, models.JobStatusFailed, map[string]interface{}{"error": errJobAbandoned.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+	go s.heartbeat(ctx, cancel, job.ID)
+
+	run := &JobRun{job: job, service: s, ctx: ctx}
+	err := run.call(fn)
+	cancel(nil)
+
+	cause := context.Cause(ctx)
+	switch {
+	case errors.Is(cause, repo.ErrJobLeaseLost) || errors.Is(err, repo.ErrJobLeaseLost):
+		// Another worker owns the job now and records the outcome
+		log.Printf("Job %d: lease lost, abandoning run", job.ID)
+		s.deleteObject(run.resultKey)
+	case err == nil:
+		s.succeed(job, run)
+	case errors.Is(err, ErrJobCancelled) || errors.Is(cause, ErrJobCancelled):
+		s.deleteObject(run.resultKey)
+		s.finish(job, models.JobStatusCancelled, nil)
+	case job.Attempts < job.MaxAttempts && !errors.As(err, new(nonRetryableError)):
+		s.deleteObject(run.resultKey)
+		delay := time.Duration(job.Attempts*job.Attempts) * 30 * time.Second
+		log.Printf("Job %d attempt %d failed, retrying in %s: %v", job.ID, job.Attempts, delay, err)
+		if err := s.repo.ReleaseJob(job.ID, s.workerID, map[string]interface{}{
+			"status":   models.JobStatusQueued,
+			"progress": 0,
+			"error":    err.Error(),
+			"run_at":   time.Now().Add(delay),
+		}); err != nil {
+			log.Printf("Failed to requeue job %d: %v", job.ID, err)
+		}
+	default:
+		s.deleteObject(run.resultKey)
+		log.Printf("Job %d failed: %v", job.ID, err)
+		s.finish(job, models.JobStatusFailed, map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// heartbeat renews the lease of a running job and cancels its context when
+// cancellation is requested or the lease was lost
+func (s *JobService) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, id uint) {
+	ticker := time.NewTicker(jobLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cancelRequested, err := s.repo.RenewLease(id, s.workerID, jobLease)
+		switch {
+		case errors.Is(err, repo.ErrJobLeaseLost):
+			cancel(err)
+			return
+		case err != nil:
+			log.Printf("Failed to renew lease of job %d: %v", id, err)
+		case cancelRequested:
+			cancel(ErrJobCancelled)
+			return
+		}
+	}
+}
+
+func (s *JobService) succeed(job *models.Job, run *JobRun) {
+	updates := map[string]interface{}{
+		"progress":    100,
+		"error":       "",
+		"result_key":  run.resultKey,
+		"result_name": run.resultName,
+		"result_type": run.resultType,
+		"result_size": run.resultSize,
+	}
+	if run.summary != nil {
+		updates["result"] = *run.summary
+	}
+	s.finish(job, models.JobStatusSucceeded, updates)
+}
+
+// finish moves a job to a final status and deletes its input
+func (s *JobService) finish(job *models.Job, status models.JobStatus, updates map[string]interface{}) {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = status
+	updates["finished_at"] = time.Now()
+
+	if err := s.repo.ReleaseJob(job.ID, s.workerID, updates); err != nil {
+		log.Printf("Failed to finish job %d: %v", job.ID, err)
+		return
+	}
+	s.deleteObject(job.InputKey)
+}
+
+func (s *JobService) deleteObject(key string) {
+	if key == "" {
+		return
+	}
+	if err := s.storage.Delete(context.Background(), key); err != nil {
+		log.Printf("Failed to delete job file %s: %v", key, err)
+	}
+}
+
+// JobRun gives a job function access to its job while it runs
+type JobRun struct {
+	job          *models.Job
+	service      *JobService
+	ctx          context.Context
+	lastProgress int
+	lastUpdate   time.Time
+
+	resultKey  string
+	resultName string
+	resultType string
+	resultSize int64
+	summary    *string
+}
+
+// call runs fn, turning a panic into an error so one bad job cannot stop the worker
+func (r *JobRun) call(fn JobFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", errJobHandlerPanicked, p)
+		}
+	}()
+	return fn(r)
+}
+
+func (r *JobRun) ID() uint {
+	return r.job.ID
+}
+
+// Context is cancelled when the job is cancelled or the worker loses it
+func (r *JobRun) Context() context.Context {
+	return r.ctx
+}
+
+// Actor is the user who submitted the job
+func (r *JobRun) Actor() Actor {
+	actor := Actor{Username: r.job.CreatorName, RequestID: r.job.RequestID, IP: r.job.IP}
+	if r.job.CreatedBy != nil {
+		actor.UserID = *r.job.CreatedBy
+	}
+	return actor
+}
+
+// Params decodes the job parameters into v
+func (r *JobRun) Params(v interface{}) error {
+	if err := json.Unmarshal([]byte(r.job.Params), v); err != nil {
+		return NonRetryable(fmt.Errorf("%w: %v", ErrInvalidJobParams, err))
+	}
+	return nil
+}
+
+// Progress records the completion percentage. It satisfies ProgressFunc and
+// returns an error once the job should stop.
+func (r *JobRun) Progress(done, total int) error {
+	if err := context.Cause(r.ctx); err != nil {
+		return err
+	}
+
+	progress := 100
+	if total > 0 {
+		progress = min(done*100/total, 99)
+	}
+	if progress == r.lastProgress || time.Since(r.lastUpdate) < jobProgressInterval {
+		return nil
+	}
+	r.lastProgress = progress
+	r.lastUpdate = time.Now()
+	return r.service.repo.UpdateJobProgress(r.job.ID, r.service.workerID, progress)
+}
+
+// OpenInput opens the input stored with the job
+func (r *JobRun) OpenInput() (io.ReadCloser, error) {
+	if r.job.InputKey == "" {
+		return nil, errors.New("job has no input")
+	}
+	return r.service.storage.Get(r.ctx, r.job.InputKey)
+}
+
+// WriteResult stores the file produced by write as the job's downloadable result.
+// The output is staged in a temporary file because storage needs the size upfront.
+func (r *JobRun) WriteResult(fileName, contentType string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp("", "job-result-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("jobs/%d/%s%s", r.job.ID, randomName(), filepath.Ext(fileName))
+	if err := r.service.storage.Put(r.ctx, key, tmp, size, contentType); err != nil {
+		return fmt.Errorf("failed to store job result: %w", err)
+	}
+	r.resultKey = key
+	r.resultName = fileName
+	r.resultType = contentType
+	r.resultSize = size
+	return nil
+}
+
+// SetSummary stores v as the job's result summary, shown with the job
+func (r *JobRun) SetSummary(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	summary := string(data)
+	r.summary = &summary
+	return nil
+}
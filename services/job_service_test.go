@@ -0,0 +1,48 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/repo"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newDryRunJobService returns a job service whose queries are built but never sent
+func newDryRunJobService(t *testing.T) *JobService {
+	t.Helper()
+	conn, err := sql.Open("pgx", "host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewJobService(repo.NewJobRepository(db), nil, 1, 0, 1)
+}
+
+func TestGetJobsPageErrors(t *testing.T) {
+	s := newDryRunJobService(t)
+
+	tests := []struct {
+		name string
+		page dtos.PageRequest
+		want error
+	}{
+		{"cursor with offset", dtos.PageRequest{Limit: 10, Offset: 5, Cursor: "abc"}, ErrCursorWithOffset},
+		{"malformed cursor", dtos.PageRequest{Limit: 10, Cursor: "not-a-cursor"}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.GetJobs(&dtos.JobFilter{}, tt.page)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
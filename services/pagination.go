@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"inventory-api/dtos"
+	"inventory-api/repo"
+)
+
+// normalizePage applies the default page size and rejects mixing cursor and offset
+func normalizePage(page dtos.PageRequest) (dtos.PageRequest, error) {
+	if page.Limit <= 0 {
+		page.Limit = 10
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	if page.Cursor != "" && page.Offset > 0 {
+		return page, ErrCursorWithOffset
+	}
+	return page, nil
+}
+
+// pageError maps repository pagination errors to service errors
+func pageError(err error) error {
+	if errors.Is(err, repo.ErrInvalidCursor) {
+		return ErrInvalidCursor
+	}
+	return err
+}
+
+// countTotal fills info.Total unless the client asked to skip counting
+func countTotal(info *dtos.PageInfo, page dtos.PageRequest, count func() (int64, error)) error {
+	if page.SkipCount {
+		return nil
+	}
+	total, err := count()
+	if err != nil {
+		return err
+	}
+	info.Total = &total
+	return nil
+}
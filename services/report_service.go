@@ -0,0 +1,540 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory-api/dtos"
+	"inventory-api/models"
+	"inventory-api/repo"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxMovementPeriods caps how many periods one movement summary may span
+const maxMovementPeriods = 366
+
+// agingBuckets are the age ranges of on-hand stock, in days. The last bucket has no upper bound.
+var agingBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{"0-30", 30},
+	{"31-60", 60},
+	{"61-90", 90},
+	{"91-180", 180},
+	{"181-365", 365},
+	{"366+", 0},
+}
+
+var movementExportHeader = []interface{}{
+	"period_start", "period_end", "product_id", "sku", "name", "opening", "in", "out", "adjustments", "closing",
+}
+
+type ReportService struct {
+	repo       *repo.ReportRepository
+	abcDefault dtos.ABCOptions
+}
+
+func NewReportService(repo *repo.ReportRepository, abcDefault dtos.ABCOptions) *ReportService {
+	return &ReportService{repo: repo, abcDefault: abcDefault}
+}
+
+// GetUserMovements summarizes who moved stock, optionally restricted by filter
+func (s *ReportService) GetUserMovements(filter *dtos.TransactionFilter) ([]dtos.UserMovementSummary, error) {
+	movements, err := s.repo.GetUserMovements(filter)
+	if err != nil {
+		return nil, err
+	}
+	return dtos.ToUserMovementSummaryList(movements), nil
+}
+
+// UserMovementReportJob runs a user_movement_report job, storing the report as
+// a JSON file
+func (s *ReportService) UserMovementReportJob(run *JobRun) error {
+	var params dtos.UserMovementReportJobParams
+	if err := run.Params(&params); err != nil {
+		return err
+	}
+
+	users, err := s.GetUserMovements(&params.Filter)
+	if err != nil {
+		return err
+	}
+	return run.WriteResult("user-movements.json", "application/json", func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+	})
+}
+
+// GetMovementSummary reports the opening balance, IN, OUT, adjustments and
+// closing balance of each product per period. Balances are rebuilt from the
+// ledger; a product appears in a period when it had stock or movements.
+func (s *ReportService) GetMovementSummary(filter *dtos.MovementFilter) ([]dtos.ProductMovementSummary, error) {
+	periods, err := movementPeriods(filter.From, filter.To, filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.repo.GetProductBalances(filter, filter.From)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.repo.GetProductMovements(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type productLedger struct {
+		sku       string
+		name      string
+		opening   int64
+		movements map[int64]models.ProductPeriodMovement
+	}
+	ledgers := make(map[uint]*productLedger)
+	ledgerFor := func(id uint, sku, name string) *productLedger {
+		ledger, ok := ledgers[id]
+		if !ok {
+			ledger = &productLedger{sku: sku, name: name, movements: make(map[int64]models.ProductPeriodMovement)}
+			ledgers[id] = ledger
+		}
+		return ledger
+	}
+	for _, balance := range balances {
+		ledgerFor(balance.ProductID, balance.SKU, balance.Name).opening = balance.Balance
+	}
+	for _, movement := range movements {
+		ledgerFor(movement.ProductID, movement.SKU, movement.Name).movements[movement.Period.Unix()] = movement
+	}
+
+	ids := make([]uint, 0, len(ledgers))
+	for id := range ledgers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	rows := []dtos.ProductMovementSummary{}
+	for _, id := range ids {
+		ledger := ledgers[id]
+		balance := ledger.opening
+		for _, period := range periods {
+			movement, moved := ledger.movements[period.bucket.Unix()]
+			if balance == 0 && !moved {
+				continue
+			}
+			row := dtos.ProductMovementSummary{
+				PeriodStart: period.start.Format(time.RFC3339),
+				PeriodEnd:   period.end.Format(time.RFC3339),
+				ProductID:   id,
+				SKU:         ledger.sku,
+				Name:        ledger.name,
+				Opening:     balance,
+				In:          movement.InQuantity,
+				Out:         movement.OutQuantity,
+				Adjustments: movement.Adjustments,
+			}
+			row.Closing = row.Opening + row.In - row.Out + row.Adjustments
+			balance = row.Closing
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// GetProductTimeseries rebuilds the stock level of one product per bucket of
+// [from, to) from the ledger. Buckets without movements are included, carrying
+// the level of the previous bucket, so the series can be charted directly.
+func (s *ReportService) GetProductTimeseries(productID uint, from, to time.Time, interval string) (*dtos.StockTimeseries, error) {
+	periods, err := movementPeriods(from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleted products still have a history
+	product, err := s.repo.GetProductByIDUnscoped(productID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	filter := &dtos.MovementFilter{From: from, To: to, GroupBy: interval, ProductID: &productID}
+	balances, err := s.repo.GetProductBalances(filter, from)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.repo.GetProductMovements(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance int64
+	for _, b := range balances {
+		balance = b.Balance
+	}
+	byBucket := make(map[int64]models.ProductPeriodMovement, len(movements))
+	for _, movement := range movements {
+		byBucket[movement.Period.Unix()] = movement
+	}
+
+	series := &dtos.StockTimeseries{
+		ProductID: product.ID,
+		SKU:       product.SKU,
+		Name:      product.Name,
+		From:      from.UTC().Format(time.RFC3339),
+		To:        to.UTC().Format(time.RFC3339),
+		Interval:  interval,
+		Points:    make([]dtos.StockLevelPoint, 0, len(periods)),
+	}
+	for _, period := range periods {
+		movement := byBucket[period.bucket.Unix()]
+		point := dtos.StockLevelPoint{
+			PeriodStart: period.start.Format(time.RFC3339),
+			PeriodEnd:   period.end.Format(time.RFC3339),
+			Opening:     balance,
+			In:          movement.InQuantity,
+			Out:         movement.OutQuantity,
+			Adjustments: movement.Adjustments,
+		}
+		point.Closing = point.Opening + point.In - point.Out + point.Adjustments
+		balance = point.Closing
+		series.Points = append(series.Points, point)
+	}
+	return series, nil
+}
+
+// WriteMovementSummary writes movement summary rows as a CSV or XLSX table
+func (s *ReportService) WriteMovementSummary(rows []dtos.ProductMovementSummary, format string, w io.Writer) error {
+	return writeTable(w, format, movementExportHeader, func(write func([]interface{}) error) error {
+		for _, row := range rows {
+			err := write([]interface{}{
+				row.PeriodStart, row.PeriodEnd, row.ProductID, row.SKU, row.Name,
+				row.Opening, row.In, row.Out, row.Adjustments, row.Closing,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// movementPeriod is one period of a movement summary: the truncated bucket the
+// ledger is grouped by, clipped to the requested range
+type movementPeriod struct {
+	bucket time.Time
+	start  time.Time
+	end    time.Time
+}
+
+// movementPeriods splits [from, to) into UTC periods of the given length
+func movementPeriods(from, to time.Time, groupBy string) ([]movementPeriod, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidReportRange)
+	}
+
+	var periods []movementPeriod
+	for bucket := truncatePeriod(from, groupBy); bucket.Before(to); {
+		if len(periods) == maxMovementPeriods {
+			return nil, fmt.Errorf("%w: the range spans more than %d periods, group by a longer period", ErrInvalidReportRange, maxMovementPeriods)
+		}
+		next := nextPeriod(bucket, groupBy)
+		start, end := bucket, next
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		periods = append(periods, movementPeriod{bucket: bucket, start: start, end: end})
+		bucket = next
+	}
+	return periods, nil
+}
+
+// truncatePeriod returns the start of the UTC period containing t, like date_trunc
+func truncatePeriod(t time.Time, groupBy string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch groupBy {
+	case dtos.MovementPeriodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case dtos.MovementPeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// nextPeriod returns the start of the period after the one starting at start
+func nextPeriod(start time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case dtos.MovementPeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case dtos.MovementPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// ABCAnalysis ranks the active products by consumption value, the OUT quantity
+// in the window times the current price. Class A goes to the top products up to
+// AThreshold percent of the total value, B to those up to BThreshold and C to
+// the rest, including products that did not move. With apply the classes are
+// stored on the products.
+func (s *ReportService) ABCAnalysis(options dtos.ABCOptions, apply bool) (*dtos.ABCReport, error) {
+	if options.WindowDays == 0 {
+		options.WindowDays = s.abcDefault.WindowDays
+	}
+	if options.AThreshold == 0 {
+		options.AThreshold = s.abcDefault.AThreshold
+	}
+	if options.BThreshold == 0 {
+		options.BThreshold = s.abcDefault.BThreshold
+	}
+	if options.BThreshold <= options.AThreshold || options.BThreshold > 100 {
+		return nil, ErrInvalidABCOptions
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -options.WindowDays)
+	consumption, err := s.repo.GetConsumptionValues(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dtos.ABCReport{
+		From:       from.Format(time.RFC3339),
+		To:         to.Format(time.RFC3339),
+		WindowDays: options.WindowDays,
+		AThreshold: options.AThreshold,
+		BThreshold: options.BThreshold,
+		Products:   make([]dtos.ABCProductResult, len(consumption)),
+	}
+	for _, product := range consumption {
+		report.TotalValue += product.ConsumptionValue
+	}
+
+	summaries := map[string]*dtos.ABCClassSummary{
+		dtos.ABCClassA: {Class: dtos.ABCClassA},
+		dtos.ABCClassB: {Class: dtos.ABCClassB},
+		dtos.ABCClassC: {Class: dtos.ABCClassC},
+	}
+	classes := make(map[string][]uint)
+	var cumulative float64
+	for i, product := range consumption {
+		result := dtos.ABCProductResult{
+			Rank:             i + 1,
+			ProductID:        product.ProductID,
+			SKU:              product.SKU,
+			Name:             product.Name,
+			Price:            product.Price,
+			OutQuantity:      product.OutQuantity,
+			ConsumptionValue: product.ConsumptionValue,
+		}
+
+		// A product belongs to the class its value starts in, so a single product
+		// worth more than the A threshold is still class A
+		result.Class = dtos.ABCClassC
+		if report.TotalValue > 0 && product.ConsumptionValue > 0 {
+			before := cumulative / report.TotalValue * 100
+			switch {
+			case before < options.AThreshold:
+				result.Class = dtos.ABCClassA
+			case before < options.BThreshold:
+				result.Class = dtos.ABCClassB
+			}
+			cumulative += product.ConsumptionValue
+			result.Share = roundShare(product.ConsumptionValue / report.TotalValue * 100)
+			result.CumulativeShare = roundShare(cumulative / report.TotalValue * 100)
+		}
+
+		summary := summaries[result.Class]
+		summary.Products++
+		summary.ConsumptionValue += product.ConsumptionValue
+		classes[result.Class] = append(classes[result.Class], product.ProductID)
+		report.Products[i] = result
+	}
+
+	for _, class := range []string{dtos.ABCClassA, dtos.ABCClassB, dtos.ABCClassC} {
+		summary := summaries[class]
+		if report.TotalValue > 0 {
+			summary.Share = roundShare(summary.ConsumptionValue / report.TotalValue * 100)
+		}
+		report.Classes = append(report.Classes, *summary)
+	}
+
+	if apply {
+		if err := s.repo.SetABCClasses(classes); err != nil {
+			return nil, err
+		}
+		report.Applied = true
+	}
+	return report, nil
+}
+
+// roundShare rounds a percentage to two decimals
+func roundShare(share float64) float64 {
+	return math.Round(share*100) / 100
+}
+
+// GetStockAging lists the products with stock on hand and no OUT movement in
+// options.Days days, or all products with stock when options.IncludeMoving is set,
+// with the value tied up in them and their on-hand quantity split by age. Ages
+// are counted from the receipts the stock is assumed to come from, first in
+// first out.
+func (s *ReportService) GetStockAging(options *dtos.AgingOptions) (*dtos.AgingReport, error) {
+	now := time.Now().UTC()
+	activity, err := s.repo.GetStockActivity(options)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := s.repo.GetStockLayers(options)
+	if err != nil {
+		return nil, err
+	}
+
+	layersByProduct := make(map[uint][]models.StockLayer)
+	for _, layer := range layers {
+		layersByProduct[layer.ProductID] = append(layersByProduct[layer.ProductID], layer)
+	}
+
+	report := &dtos.AgingReport{
+		AsOf:     now.Format(time.RFC3339),
+		Days:     options.Days,
+		Products: []dtos.ProductAging{},
+		Buckets:  newAgingBuckets(),
+	}
+	cutoff := now.AddDate(0, 0, -options.Days)
+	for _, product := range activity {
+		idleSince := product.CreatedAt
+		if product.LastOutAt != nil {
+			idleSince = *product.LastOutAt
+		}
+		dead := idleSince.Before(cutoff)
+		if !dead && !options.IncludeMoving {
+			continue
+		}
+
+		row := dtos.ProductAging{
+			ProductID:  product.ProductID,
+			SKU:        product.SKU,
+			Name:       product.Name,
+			CategoryID: product.CategoryID,
+			Price:      product.Price,
+			Quantity:   product.Quantity,
+			Value:      roundMoney(float64(product.Quantity) * product.Price),
+			LastInAt:   formatOptionalTime(product.LastInAt),
+			LastOutAt:  formatOptionalTime(product.LastOutAt),
+			IdleDays:   int(now.Sub(idleSince).Hours() / 24),
+			Dead:       dead,
+			Buckets:    newAgingBuckets(),
+		}
+		for _, layer := range layersByProduct[product.ProductID] {
+			i := agingBucketIndex(int(now.Sub(layer.ReceivedAt).Hours() / 24))
+			value := float64(layer.Quantity) * product.Price
+			row.Buckets[i].Quantity += layer.Quantity
+			row.Buckets[i].Value = roundMoney(row.Buckets[i].Value + value)
+			report.Buckets[i].Quantity += layer.Quantity
+			report.Buckets[i].Value = roundMoney(report.Buckets[i].Value + value)
+		}
+
+		report.TotalQuantity += int64(product.Quantity)
+		report.TotalValue = roundMoney(report.TotalValue + row.Value)
+		report.Products = append(report.Products, row)
+	}
+
+	sortAging(report.Products, options.Sort)
+	return report, nil
+}
+
+// WriteStockAging writes the products of an aging report as a CSV or XLSX table,
+// one quantity column per age bucket
+func (s *ReportService) WriteStockAging(report *dtos.AgingReport, format string, w io.Writer) error {
+	header := []interface{}{"product_id", "sku", "name", "category_id", "price", "quantity", "value", "last_in_at", "last_out_at", "idle_days", "dead"}
+	for _, bucket := range agingBuckets {
+		header = append(header, "qty_"+bucket.label)
+	}
+
+	return writeTable(w, format, header, func(write func([]interface{}) error) error {
+		for _, product := range report.Products {
+			row := []interface{}{
+				product.ProductID, product.SKU, product.Name, optionalCell(product.CategoryID),
+				product.Price, product.Quantity, product.Value,
+				optionalString(product.LastInAt), optionalString(product.LastOutAt),
+				product.IdleDays, fmt.Sprint(product.Dead),
+			}
+			for _, bucket := range product.Buckets {
+				row = append(row, bucket.Quantity)
+			}
+			if err := write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func newAgingBuckets() []dtos.AgingBucketAmount {
+	buckets := make([]dtos.AgingBucketAmount, len(agingBuckets))
+	for i, bucket := range agingBuckets {
+		buckets[i].Bucket = bucket.label
+	}
+	return buckets
+}
+
+// agingBucketIndex returns the bucket stock of the given age in days falls in
+func agingBucketIndex(days int) int {
+	for i, bucket := range agingBuckets {
+		if bucket.maxDays == 0 || days <= bucket.maxDays {
+			return i
+		}
+	}
+	return len(agingBuckets) - 1
+}
+
+// sortAging orders aging rows by a sort field, prefixed with - for descending.
+// Ties keep product ID order.
+func sortAging(products []dtos.ProductAging, field string) {
+	desc := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := products[i], products[j]
+		var less, greater bool
+		switch field {
+		case "idle_days":
+			less, greater = a.IdleDays < b.IdleDays, a.IdleDays > b.IdleDays
+		case "quantity":
+			less, greater = a.Quantity < b.Quantity, a.Quantity > b.Quantity
+		case "sku":
+			less, greater = a.SKU < b.SKU, a.SKU > b.SKU
+		default:
+			less, greater = a.Value < b.Value, a.Value > b.Value
+		}
+		if less == greater {
+			return a.ProductID < b.ProductID
+		}
+		if desc {
+			return greater
+		}
+		return less
+	})
+}
+
+// roundMoney rounds an amount to whole cents
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.UTC().Format(time.RFC3339)
+	return &formatted
+}
@@ -2,24 +2,29 @@ package services
 
 import (
 	"errors"
+	"inventory-api/dtos"
 	"inventory-api/models"
 	"inventory-api/repo"
 	"inventory-api/utils"
+
+	"gorm.io/gorm"
 )
 
 type UserService struct {
 	userRepo  repo.UserRepositoryInterface
 	jwtSecret string
+	audit     *AuditService
 }
 
-func NewUserService(userRepo repo.UserRepositoryInterface, jwtSecret string) *UserService {
+func NewUserService(userRepo repo.UserRepositoryInterface, jwtSecret string, audit *AuditService) *UserService {
 	return &UserService{
 		userRepo:  userRepo,
 		jwtSecret: jwtSecret,
+		audit:     audit,
 	}
 }
 
-func (s *UserService) Register(username, password, email, phone, role string) (*models.User, error) {
+func (s *UserService) Register(username, password, email, phone, role string, actor Actor) (*models.User, error) {
 	// Validate role
 	if !models.IsValidRole(role) {
 		return nil, errors.New("invalid role")
@@ -57,7 +62,20 @@ func (s *UserService) Register(username, password, email, phone, role string) (*
 		Role:           role,
 	}
 
-	return s.userRepo.CreateUser(user)
+	var created *models.User
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		var err error
+		created, err = s.userRepo.WithTx(tx).CreateUser(user)
+		if err != nil {
+			return err
+		}
+		audit.Record(AuditEntityUser, created.ID, AuditActionCreate, nil, dtos.ToUserResponse(created))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (s *UserService) Login(username, password string) (string, *models.User, error) {
@@ -86,16 +104,36 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	return s.userRepo.GetUserByUsername(username)
 }
 
-func (s *UserService) GetAllUsers(limit, offset int) ([]models.User, error) {
-	return s.userRepo.GetAllUsers(limit, offset)
+func (s *UserService) GetAllUsers(page dtos.PageRequest) ([]models.User, dtos.PageInfo, error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+
+	users, info, err := s.userRepo.GetAllUsers(page)
+	if err != nil {
+		return nil, dtos.PageInfo{}, pageError(err)
+	}
+	if err := countTotal(&info, page, s.userRepo.CountUsers); err != nil {
+		return nil, dtos.PageInfo{}, err
+	}
+	return users, info, nil
 }
 
-func (s *UserService) UpdateUser(id uint, email, phone, role string) (*models.User, error) {
+// UpdateUser updates the given fields. When expectedVersion is non-zero the
+// update only succeeds if the user is still at that version.
+func (s *UserService) UpdateUser(id uint, email, phone, role string, expectedVersion uint, actor Actor) (*models.User, error) {
 	user, err := s.userRepo.GetUserByID(id)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
+	if expectedVersion != 0 && user.Version != expectedVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	previous := *user
+
 	// Validate and update email
 	if email != "" {
 		// Check if email is already taken by another user
@@ -119,10 +157,26 @@ func (s *UserService) UpdateUser(id uint, email, phone, role string) (*models.Us
 		user.Role = role
 	}
 
-	return s.userRepo.UpdateUser(user)
+	var updated *models.User
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		var err error
+		updated, err = s.userRepo.WithTx(tx).UpdateUser(user, &previous, expectedVersion)
+		if err != nil {
+			return err
+		}
+		audit.Record(AuditEntityUser, id, AuditActionUpdate, dtos.ToUserResponse(&previous), dtos.ToUserResponse(updated))
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionConflict) {
+			return nil, ErrVersionMismatch
+		}
+		return nil, err
+	}
+	return updated, nil
 }
 
-func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string) error {
+func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string, actor Actor) error {
 	user, err := s.userRepo.GetUserByID(id)
 	if err != nil {
 		return errors.New("user not found")
@@ -147,14 +201,33 @@ func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string) e
 		return errors.New("failed to process password")
 	}
 
+	previous := *user
 	user.PasswordHashed = hashedPassword
-	_, err = s.userRepo.UpdateUser(user)
+	err = s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if _, err := s.userRepo.WithTx(tx).UpdateUser(user, &previous, 0); err != nil {
+			return err
+		}
+		// Only the fact of the change is recorded, never the hashes
+		audit.Record(AuditEntityUser, id, AuditActionPasswordChange, nil, nil)
+		return nil
+	})
 	if err != nil {
 		return errors.New("failed to update password")
 	}
 	return nil
 }
 
-func (s *UserService) DeleteUser(id uint) error {
-	return s.userRepo.DeleteUser(id)
+func (s *UserService) DeleteUser(id uint, actor Actor) error {
+	user, err := s.userRepo.GetUserByID(id)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	return s.audit.Transaction(actor, func(tx *gorm.DB, audit *AuditRecorder) error {
+		if err := s.userRepo.WithTx(tx).DeleteUser(id); err != nil {
+			return err
+		}
+		audit.Record(AuditEntityUser, id, AuditActionDelete, dtos.ToUserResponse(user), nil)
+		return nil
+	})
 }
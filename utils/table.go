@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported tabular file formats
+const (
+	TableFormatCSV  = "csv"
+	TableFormatXLSX = "xlsx"
+)
+
+// maxUnzippedSize bounds how much an XLSX file may expand to, guarding against zip bombs
+const maxUnzippedSize = 512 << 20
+
+// xlsxMaxRows is the most rows a single worksheet can hold
+const xlsxMaxRows = excelize.TotalRows
+
+// ErrUnsupportedTableFormat is returned for files that are neither CSV nor XLSX
+var ErrUnsupportedTableFormat = errors.New("unsupported file format, expected csv or xlsx")
+
+// TableReader reads the rows of a CSV file or spreadsheet one at a time. Next
+// returns io.EOF after the last row.
+type TableReader interface {
+	Next() ([]string, error)
+	Close() error
+}
+
+// DetectTableFormat guesses the format from the file name, falling back to the
+// content: XLSX files are zip archives, anything else is treated as CSV
+func DetectTableFormat(fileName string, head []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return TableFormatCSV
+	case ".xlsx":
+		return TableFormatXLSX
+	}
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return TableFormatXLSX
+	}
+	return TableFormatCSV
+}
+
+// OpenTable opens r as the given format. sheet selects an XLSX worksheet and
+// defaults to the active one.
+func OpenTable(r io.Reader, format, sheet string) (TableReader, error) {
+	switch format {
+	case TableFormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return &csvTable{reader: reader}, nil
+	case TableFormatXLSX:
+		return openXLSXTable(r, sheet)
+	}
+	return nil, ErrUnsupportedTableFormat
+}
+
+type csvTable struct {
+	reader *csv.Reader
+	read   bool
+}
+
+func (t *csvTable) Next() ([]string, error) {
+	record, err := t.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet programs often prepend a byte order mark
+	if !t.read && len(record) > 0 {
+		record[0] = strings.TrimPrefix(record[0], "\ufeff")
+	}
+	t.read = true
+	return record, nil
+}
+
+func (t *csvTable) Close() error {
+	return nil
+}
+
+type xlsxTable struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+func openXLSXTable(r io.Reader, sheet string) (*xlsxTable, error) {
+	file, err := excelize.OpenReader(r, excelize.Options{UnzipSizeLimit: maxUnzippedSize})
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %w", err)
+	}
+
+	if sheet == "" {
+		sheet = file.GetSheetName(file.GetActiveSheetIndex())
+	}
+	rows, err := file.Rows(sheet)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	return &xlsxTable{file: file, rows: rows}, nil
+}
+
+func (t *xlsxTable) Next() ([]string, error) {
+	if !t.rows.Next() {
+		if err := t.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return t.rows.Columns()
+}
+
+func (t *xlsxTable) Close() error {
+	if err := t.rows.Close(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// TableWriter writes rows to a CSV file or spreadsheet. Cells may be strings,
+// numbers, times or nil. Close flushes the output; Abort releases the writer
+// after a failure without completing a spreadsheet.
+type TableWriter interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+	Abort()
+}
+
+// NewTableWriter writes the given format to w. XLSX rows are buffered by the
+// stream writer, which spills to a temporary file for large sheets, and the
+// workbook is written out on Close.
+func NewTableWriter(w io.Writer, format string) (TableWriter, error) {
+	switch format {
+	case TableFormatCSV:
+		return &csvTableWriter{writer: csv.NewWriter(w)}, nil
+	case TableFormatXLSX:
+		return newXLSXTableWriter(w)
+	}
+	return nil, ErrUnsupportedTableFormat
+}
+
+type csvTableWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func (t *csvTableWriter) WriteRow(cells []interface{}) error {
+	t.record = t.record[:0]
+	for _, cell := range cells {
+		t.record = append(t.record, formatCell(cell))
+	}
+	return t.writer.Write(t.record)
+}
+
+func (t *csvTableWriter) Close() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+func (t *csvTableWriter) Abort() {
+	t.writer.Flush()
+}
+
+// formatCell renders a cell value as CSV text
+func formatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
+
+type xlsxTableWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXTableWriter(w io.Writer) (*xlsxTableWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &xlsxTableWriter{out: w, file: file, stream: stream}, nil
+}
+
+func (t *xlsxTableWriter) WriteRow(cells []interface{}) error {
+	if t.row >= xlsxMaxRows {
+		return fmt.Errorf("xlsx sheets hold at most %d rows, use csv or ndjson", xlsxMaxRows)
+	}
+	t.row++
+	cell, err := excelize.CoordinatesToCellName(1, t.row)
+	if err != nil {
+		return err
+	}
+	// Spreadsheets have no timezone, times are written as UTC text
+	for i, value := range cells {
+		if v, ok := value.(time.Time); ok {
+			cells[i] = v.UTC().Format(time.RFC3339)
+		}
+	}
+	return t.stream.SetRow(cell, cells)
+}
+
+func (t *xlsxTableWriter) Close() error {
+	defer t.file.Close()
+	if err := t.stream.Flush(); err != nil {
+		return err
+	}
+	return t.file.Write(t.out)
+}
+
+func (t *xlsxTableWriter) Abort() {
+	t.file.Close()
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	// Register GIF decoder for image.Decode
+	_ "image/gif"
+)
+
+// maxImagePixels caps the width times height of images GenerateThumbnail decodes.
+// A few KB of compressed pixels can declare dimensions that take gigabytes to decode.
+const maxImagePixels = 40_000_000
+
+// ErrImageTooLarge is returned for images with more than maxImagePixels pixels
+var ErrImageTooLarge = errors.New("image dimensions exceed the maximum allowed")
+
+// GenerateThumbnail decodes an image and scales it down so its longest side is
+// at most maxSize pixels. JPEG input produces a JPEG thumbnail, anything else PNG.
+// It returns the encoded thumbnail and its content type.
+func GenerateThumbnail(data []byte, maxSize int) ([]byte, string, error) {
+	// Check the dimensions in the header before allocating the decoded image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	thumb := resizeImage(src, maxSize)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, thumb); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// resizeImage scales src down with box sampling, keeping the aspect ratio
+func resizeImage(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return src
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = srcH * maxSize / srcW
+	} else {
+		dstW = srcW * maxSize / srcH
+	}
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	// columns maps every source column to the destination column it is averaged into
+	columns := make([]int, srcW)
+	for x := 0; x < dstW; x++ {
+		for sx := x * srcW / dstW; sx < (x+1)*srcW/dstW; sx++ {
+			columns[sx] = x
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	// Source rows are converted to RGBA one at a time. draw has fast paths for
+	// the image types the decoders return, so pixels are read straight from
+	// their buffers instead of through color.Color.
+	row := image.NewRGBA(image.Rect(0, 0, srcW, 1))
+	sums := make([]uint64, 4*dstW)
+	counts := make([]uint64, dstW)
+	for y := 0; y < dstH; y++ {
+		clear(sums)
+		clear(counts)
+
+		// Add up every source pixel that maps onto this destination row
+		for sy := y * srcH / dstH; sy < (y+1)*srcH/dstH; sy++ {
+			draw.Draw(row, row.Rect, src, image.Pt(bounds.Min.X, bounds.Min.Y+sy), draw.Src)
+			for sx, x := range columns {
+				pixel := row.Pix[4*sx : 4*sx+4 : 4*sx+4]
+				sum := sums[4*x : 4*x+4 : 4*x+4]
+				sum[0] += uint64(pixel[0])
+				sum[1] += uint64(pixel[1])
+				sum[2] += uint64(pixel[2])
+				sum[3] += uint64(pixel[3])
+				counts[x]++
+			}
+		}
+
+		out := dst.Pix[y*dst.Stride : y*dst.Stride+4*dstW]
+		for x, n := range counts {
+			if n == 0 {
+				continue
+			}
+			for c := 0; c < 4; c++ {
+				out[4*x+c] = uint8((sums[4*x+c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns the signature and IHDR chunk of a PNG declaring the given
+// dimensions, which is all image.DecodeConfig reads
+func pngHeader(width, height uint32) []byte {
+	var chunk bytes.Buffer
+	chunk.WriteString("IHDR")
+	binary.Write(&chunk, binary.BigEndian, width)
+	binary.Write(&chunk, binary.BigEndian, height)
+	chunk.Write([]byte{8, 6, 0, 0, 0}) // 8-bit RGBA, no interlace
+
+	var data bytes.Buffer
+	data.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&data, binary.BigEndian, uint32(chunk.Len()-4))
+	data.Write(chunk.Bytes())
+	binary.Write(&data, binary.BigEndian, crc32.ChecksumIEEE(chunk.Bytes()))
+	return data.Bytes()
+}
+
+func TestGenerateThumbnailRejectsHugeImages(t *testing.T) {
+	_, _, err := GenerateThumbnail(pngHeader(10000, 10000), 256)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	src := gradient(640, 480)
+
+	tests := []struct {
+		name        string
+		encode      func(*bytes.Buffer) error
+		contentType string
+	}{
+		{"jpeg", func(buf *bytes.Buffer) error { return jpeg.Encode(buf, src, nil) }, "image/jpeg"},
+		{"png", func(buf *bytes.Buffer) error { return png.Encode(buf, src) }, "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			thumb, contentType, err := GenerateThumbnail(buf.Bytes(), 256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.contentType)
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != 256 || config.Height != 192 {
+				t.Errorf("thumbnail is %dx%d, want 256x192", config.Width, config.Height)
+			}
+		})
+	}
+}
+
+// TestResizeImage compares resizeImage with averaging every pixel through
+// image.Image.At, for each image type the decoders return
+func TestResizeImage(t *testing.T) {
+	src := gradient(301, 157)
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	ycbcr, err := jpeg.Decode(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paletted := image.NewPaletted(src.Rect, palette.Plan9)
+	gray := image.NewGray(src.Rect)
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			paletted.Set(x, y, src.At(x, y))
+			gray.Set(x, y, src.At(x, y))
+		}
+	}
+
+	images := map[string]image.Image{
+		"nrgba":    src,
+		"ycbcr":    ycbcr,
+		"paletted": paletted,
+		"gray":     gray,
+		"subimage": src.SubImage(image.Rect(13, 7, 280, 150)),
+	}
+	for name, img := range images {
+		t.Run(name, func(t *testing.T) {
+			got := resizeImage(img, 64)
+			want := referenceResize(img, 64)
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+			}
+			for y := 0; y < want.Rect.Dy(); y++ {
+				for x := 0; x < want.Rect.Dx(); x++ {
+					g := got.(*image.RGBA).RGBAAt(x, y)
+					w := want.RGBAAt(x, y)
+					if diff(g.R, w.R) > 1 || diff(g.G, w.G) > 1 || diff(g.B, w.B) > 1 || diff(g.A, w.A) > 1 {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResizeImageKeepsSmallImages(t *testing.T) {
+	src := gradient(100, 50)
+	if got := resizeImage(src, 256); got != image.Image(src) {
+		t.Errorf("image smaller than the thumbnail was resized")
+	}
+}
+
+// gradient returns a translucent image whose pixels all differ
+func gradient(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x * y), A: uint8(128 + (x+y)%128)})
+		}
+	}
+	return img
+}
+
+// referenceResize box samples src to the size resizeImage picks, one pixel at a time
+func referenceResize(src image.Image, maxSize int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = srcH * maxSize / srcW
+	} else {
+		dstW = srcW * maxSize / srcH
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var r, g, b, a, n uint64
+			for sy := bounds.Min.Y + y*srcH/dstH; sy < bounds.Min.Y+(y+1)*srcH/dstH; sy++ {
+				for sx := bounds.Min.X + x*srcW/dstW; sx < bounds.Min.X+(x+1)*srcW/dstW; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+func diff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}